@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io"
+	"log"
+	"sync"
+)
+
+// asyncLogWriter hands the log lines to a goroutine writing them, so
+// requests don't wait for the output. It writes synchronously until
+// started, and again once flushed, so startup and exit messages are never
+// lost.
+type asyncLogWriter struct {
+	sync.Mutex
+	out   io.Writer
+	drop  bool
+	lines chan []byte
+	done  chan struct{}
+}
+
+// asyncLog is set when --log-async is enabled
+var asyncLog *asyncLogWriter
+
+func newAsyncLogWriter(out io.Writer, size int, drop bool) *asyncLogWriter {
+	return &asyncLogWriter{out: out, drop: drop, lines: make(chan []byte, size)}
+}
+
+func (w *asyncLogWriter) Write(b []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	if w.done == nil {
+		return w.out.Write(b)
+	}
+	// the log package reuses its buffer
+	line := append([]byte(nil), b...)
+	if w.drop {
+		select {
+		case w.lines <- line:
+		default:
+		}
+		return len(b), nil
+	}
+	w.lines <- line
+	return len(b), nil
+}
+
+// start writes the lines from a goroutine from now on
+func (w *asyncLogWriter) start() {
+	w.Lock()
+	defer w.Unlock()
+	if w.done != nil {
+		return
+	}
+	w.lines = make(chan []byte, cap(w.lines))
+	w.done = make(chan struct{})
+	go func(lines chan []byte, done chan struct{}) {
+		for line := range lines {
+			_, _ = w.out.Write(line)
+		}
+		close(done)
+	}(w.lines, w.done)
+}
+
+// flush writes the pending lines, and goes back to synchronous writes
+func (w *asyncLogWriter) flush() {
+	w.Lock()
+	defer w.Unlock()
+	if w.done == nil {
+		return
+	}
+	close(w.lines)
+	<-w.done
+	w.done = nil
+}
+
+// setAsyncLog buffers up to size log lines between the logger and the
+// output. When the buffer is full, new lines are dropped if drop is set,
+// otherwise logging blocks.
+func setAsyncLog(size int, drop bool) {
+	asyncLog = newAsyncLogWriter(logOutputWriter, size, drop)
+	logOutputWriter = asyncLog
+	log.SetOutput(asyncLog)
+}
+
+func startAsyncLog() {
+	if asyncLog != nil {
+		asyncLog.start()
+	}
+}
+
+// flushLogs writes the pending log lines, it must be called before exiting
+func flushLogs() {
+	if asyncLog != nil {
+		asyncLog.flush()
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PierreZ/goStatic/gostatic"
+)
+
+// slowWriter is an output taking its time to write each line
+type slowWriter struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *slowWriter) Write(b []byte) (int, error) {
+	time.Sleep(100 * time.Microsecond)
+	w.Lock()
+	defer w.Unlock()
+	return w.buf.Write(b)
+}
+
+func (w *slowWriter) String() string {
+	w.Lock()
+	defer w.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncLogFlushedOnShutdown(t *testing.T) {
+	out := &slowWriter{}
+	w := newAsyncLogWriter(out, 1024, false)
+	w.start()
+
+	s, err := gostatic.New(gostatic.Config{
+		Path:          t.TempDir(),
+		EnableLogging: true,
+		LogFormat:     "json",
+		AccessLog:     w,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const requests = 200
+	for i := 0; i < requests; i++ {
+		s.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", fmt.Sprintf("/missing/%v", i), nil))
+	}
+	w.flush()
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != requests {
+		t.Fatalf("got %v log lines once flushed, want %v", len(lines), requests)
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, fmt.Sprintf(`"/missing/%v"`, i)) {
+			t.Errorf("line %v: got %v, want the request %v", i, line, i)
+		}
+	}
+
+	// once flushed, the lines are written synchronously again
+	fmt.Fprintln(w, "exit message")
+	if !strings.HasSuffix(out.String(), "exit message\n") {
+		t.Error("line written after the flush is missing")
+	}
+}
+
+func TestAsyncLogDrop(t *testing.T) {
+	blocked := make(chan struct{})
+	out := &blockingWriter{unblock: blocked}
+	w := newAsyncLogWriter(out, 4, true)
+	w.start()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			fmt.Fprintln(w, "line", i)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging blocked while dropping lines")
+	}
+	close(blocked)
+	w.flush()
+	// the line being written, and the buffered ones
+	if out.lines < 1 || out.lines > 5 {
+		t.Errorf("got %v lines written, want at most the 4 buffered and the one being written", out.lines)
+	}
+}
+
+// blockingWriter blocks its writes until unblock is closed
+type blockingWriter struct {
+	unblock chan struct{}
+	lines   int
+}
+
+func (w *blockingWriter) Write(b []byte) (int, error) {
+	<-w.unblock
+	w.lines++
+	return len(b), nil
+}
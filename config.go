@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// configDecoders decode the configuration files by extension. YAML is only
+// available when built with the yaml tag.
+var configDecoders = map[string]func(data []byte, v interface{}) error{
+	".json": json.Unmarshal,
+}
+
+// envName is the environment variable of a flag, e.g. GOSTATIC_ENABLE_LOGGING
+func envName(flagName string) string {
+	return "GOSTATIC_" + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// readConfigFile reads a configuration file of flag names and values
+func readConfigFile(path string) (map[string]interface{}, error) {
+	decode, ok := configDecoders[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, fmt.Errorf("unsupported config file %v, must be .json, or .yaml with a build with the yaml tag", path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	if err := decode(data, &values); err != nil {
+		return nil, fmt.Errorf("invalid config file %v: %v", path, err)
+	}
+	return values, nil
+}
+
+// setFlagFromConfig sets a flag from a config file value. Lists set a
+// repeatable flag once per item.
+func setFlagFromConfig(name string, value interface{}) error {
+	if items, ok := value.([]interface{}); ok {
+		for _, item := range items {
+			if err := flag.Set(name, fmt.Sprint(item)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// JSON numbers are float64, keep integers as such
+	if f, ok := value.(float64); ok && f == float64(int64(f)) {
+		value = int64(f)
+	}
+	return flag.Set(name, fmt.Sprint(value))
+}
+
+// applyConfig sets the flags not given on the command line from the
+// GOSTATIC_* environment variables, then from the config file. Flags win
+// over environment variables, which win over the file. The port also comes
+// from PORT, after GOSTATIC_PORT. Unknown keys of the file are only warned
+// about.
+func applyConfig() error {
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] {
+			return
+		}
+		if value, ok := os.LookupEnv(envName(f.Name)); ok {
+			if setErr := flag.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid %v: %v", envName(f.Name), setErr)
+			}
+			explicit[f.Name] = true
+		}
+	})
+	// PaaS platforms like Heroku or Cloud Run give the port to bind in PORT
+	if value, ok := os.LookupEnv("PORT"); ok && err == nil && !explicit["port"] {
+		if err = flag.Set("port", value); err != nil {
+			err = fmt.Errorf("invalid PORT: %v", err)
+		} else {
+			log.Println("Using port " + value + " from the PORT environment variable")
+		}
+		explicit["port"] = true
+	}
+	configPath := *configFile
+	if err != nil || configPath == "" {
+		return err
+	}
+
+	values, err := readConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if flag.Lookup(name) == nil || name == "config" {
+			log.Printf("Warning: unknown key %q in config file %v\n", name, configPath)
+			continue
+		}
+		if explicit[name] {
+			continue
+		}
+		if err := setFlagFromConfig(name, values[name]); err != nil {
+			return fmt.Errorf("invalid %q in config file %v: %v", name, configPath, err)
+		}
+	}
+	return nil
+}
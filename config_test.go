@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+// parsePortFlags parses args with a command line only holding the port
+// and config flags, restored when the test ends
+func parsePortFlags(t *testing.T, args ...string) {
+	t.Helper()
+	commandLine, port, config := flag.CommandLine, *portPtr, *configFile
+	t.Cleanup(func() {
+		flag.CommandLine, *portPtr, *configFile = commandLine, port, config
+	})
+	flag.CommandLine = flag.NewFlagSet("goStatic", flag.ContinueOnError)
+	flag.IntVar(portPtr, "port", 1080, "")
+	flag.StringVar(configFile, "config", "", "")
+	if err := flag.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setenv sets an environment variable for the test, or unsets it when value
+// is empty
+func setenv(t *testing.T, name, value string) {
+	t.Setenv(name, value)
+	if value == "" {
+		os.Unsetenv(name)
+	}
+}
+
+func TestPortFromEnv(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		port         string
+		gostaticPort string
+		want         int
+		wantErr      bool
+	}{
+		{"default", nil, "", "", 1080, false},
+		{"PORT", nil, "8081", "", 8081, false},
+		{"--port wins", []string{"--port=9000"}, "8081", "", 9000, false},
+		{"GOSTATIC_PORT wins", nil, "8081", "7000", 7000, false},
+		{"invalid PORT", nil, "http", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, "PORT", tt.port)
+			setenv(t, "GOSTATIC_PORT", tt.gostaticPort)
+			parsePortFlags(t, tt.args...)
+
+			err := applyConfig()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got port %v, want an error", *portPtr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *portPtr != tt.want {
+				t.Errorf("got port %v, want %v", *portPtr, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package gostatic
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// authMiddleware checks basic auth
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
+
+		auth := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+
+		if len(auth) != 2 || auth[0] != "Basic" {
+			http.Error(w, "authorization failed", http.StatusUnauthorized)
+			return
+		}
+
+		payload, _ := base64.StdEncoding.DecodeString(auth[1])
+		pair := strings.SplitN(string(payload), ":", 2)
+
+		if len(pair) != 2 {
+			http.Error(w, "authorization failed", http.StatusUnauthorized)
+			return
+		}
+
+		if s.htpasswdUsers != nil {
+			if !checkHtpasswd(s.htpasswdUsers, pair[0], pair[1]) {
+				http.Error(w, "authorization failed", http.StatusUnauthorized)
+				return
+			}
+		} else if strings.Compare(pair[0], s.username) != 0 || strings.Compare(pair[1], s.password) != 0 {
+			http.Error(w, "authorization failed", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) parseAuth(auth string) error {
+	identity := strings.Split(auth, ":")
+	if len(identity) != 2 {
+		return errors.New("basic auth must be like this: user:password")
+	}
+
+	s.username = identity[0]
+	s.password = identity[1]
+	return nil
+}
+
+func (s *Server) generateRandomAuth() error {
+	password, err := generateRandomString(s.cfg.PasswordLength)
+	if err != nil {
+		return err
+	}
+	s.username = s.cfg.DefaultUser
+	s.password = password
+	log.Printf("User generated for basic auth. User:'%v', password:'%v'\n", s.username, s.password)
+	return nil
+}
+
+func generateRandomString(size int) (string, error) {
+
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%X", b), nil
+}
+
+// authPathsMiddleware only asks for basic auth on the paths under one of
+// the prefixes. Paths are cleaned first, so /public/../admin/ can't get
+// around the /admin/ prefix.
+func (s *Server) authPathsMiddleware(prefixes []string, next http.Handler) http.Handler {
+	protected := s.authMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestPath := path.Clean("/" + r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/") && requestPath != "/" {
+			requestPath += "/"
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(requestPath, prefix) || requestPath == strings.TrimSuffix(prefix, "/") {
+				protected.ServeHTTP(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,10 @@
+//go:build bcrypt
+// +build bcrypt
+
+package gostatic
+
+import "golang.org/x/crypto/bcrypt"
+
+func init() {
+	bcryptCompare = bcrypt.CompareHashAndPassword
+}
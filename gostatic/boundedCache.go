@@ -0,0 +1,56 @@
+package gostatic
+
+import "sync"
+
+// boundedCache is an in-memory cache holding at most maxSize bytes of
+// values. Random entries are evicted to make room for new ones.
+type boundedCache struct {
+	sync.RWMutex
+	maxSize int
+	size    int
+	entries map[string]boundedEntry
+}
+
+// boundedEntry is a cached value and its size in bytes
+type boundedEntry struct {
+	value interface{}
+	size  int
+}
+
+func newBoundedCache(maxSize int) *boundedCache {
+	return &boundedCache{
+		maxSize: maxSize,
+		entries: make(map[string]boundedEntry),
+	}
+}
+
+func (c *boundedCache) get(key string) (interface{}, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	entry, ok := c.entries[key]
+	return entry.value, ok
+}
+
+// add caches value, of size bytes, in place of the entry of key if any.
+// Values larger than the whole cache are not kept.
+func (c *boundedCache) add(key string, value interface{}, size int) {
+	if size > c.maxSize {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	if old, ok := c.entries[key]; ok {
+		delete(c.entries, key)
+		c.size -= old.size
+	}
+	// evict random entries until the new one fits
+	for k, v := range c.entries {
+		if c.size+size <= c.maxSize {
+			break
+		}
+		delete(c.entries, k)
+		c.size -= v.size
+	}
+	c.entries[key] = boundedEntry{value: value, size: size}
+	c.size += size
+}
@@ -0,0 +1,37 @@
+package gostatic
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBoundedCache(t *testing.T) {
+	c := newBoundedCache(10)
+
+	c.add("a", "first", 4)
+	c.add("a", "second", 4)
+	if value, ok := c.get("a"); !ok || value != "second" {
+		t.Errorf("got %v %v, want the replacing value", value, ok)
+	}
+	if c.size != 4 {
+		t.Errorf("got size %v after replacing, want 4", c.size)
+	}
+
+	c.add("too large", "value", 11)
+	if _, ok := c.get("too large"); ok {
+		t.Error("a value larger than the cache was kept")
+	}
+
+	for i := 0; i < 20; i++ {
+		c.add(fmt.Sprint(i), i, 3)
+		if c.size > 10 {
+			t.Fatalf("got size %v, want at most 10", c.size)
+		}
+		if value, ok := c.get(fmt.Sprint(i)); !ok || value != i {
+			t.Fatalf("got %v %v, want the value just added", value, ok)
+		}
+	}
+	if len(c.entries) != 3 || c.size != 9 {
+		t.Errorf("got %v entries of %v bytes, want 3 of 9", len(c.entries), c.size)
+	}
+}
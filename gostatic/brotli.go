@@ -0,0 +1,16 @@
+//go:build brotli
+// +build brotli
+
+package gostatic
+
+import (
+	"io/ioutil"
+
+	"github.com/andybalholm/brotli"
+)
+
+func init() {
+	newBrotliWriter = func() encoder {
+		return brotli.NewWriter(ioutil.Discard)
+	}
+}
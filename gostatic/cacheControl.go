@@ -0,0 +1,96 @@
+package gostatic
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ImmutableCacheControl lets clients keep a file for a year without ever
+// revalidating it
+const ImmutableCacheControl = "public, max-age=31536000, immutable"
+
+// htmlNoCacheWriter makes sure HTML pages are revalidated, whatever the
+// Cache-Control set by the other rules, so deploys take effect at once.
+// htmlPath is set when the request path is an HTML page: the 304 responses
+// have no Content-Type to tell.
+type htmlNoCacheWriter struct {
+	http.ResponseWriter
+	htmlPath    bool
+	wroteHeader bool
+}
+
+func (w *htmlNoCacheWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		if w.htmlPath || mediaType == "text/html" {
+			w.Header().Set("Cache-Control", "no-cache, must-revalidate")
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *htmlNoCacheWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// isHTMLPath tells whether a request path is an HTML page, from its
+// extension, or a directory served with its index.html
+func isHTMLPath(requestPath string) bool {
+	if strings.HasSuffix(requestPath, "/") {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(mime.TypeByExtension(path.Ext(requestPath)))
+	return mediaType == "text/html"
+}
+
+// htmlNoCacheMiddleware forces Cache-Control: no-cache, must-revalidate on
+// HTML responses. It must wrap the other header rules to take precedence.
+func htmlNoCacheMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&htmlNoCacheWriter{ResponseWriter: w, htmlPath: isHTMLPath(r.URL.Path)}, r)
+	})
+}
+
+// immutableWriter sets the immutable Cache-Control on the successful
+// responses only, a missing fingerprinted file must not be cached for a year
+type immutableWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *immutableWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status == http.StatusOK || status == http.StatusPartialContent || status == http.StatusNotModified {
+			w.Header().Set("Cache-Control", ImmutableCacheControl)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *immutableWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// immutableMiddleware marks the files whose path matches pattern, usually
+// fingerprinted assets like main.8f3a1c.js, as immutable. It overrides the
+// default Cache-Control and the header config rules. Missing files are
+// left alone, the fallback page served instead must not be kept.
+func immutableMiddleware(fs http.FileSystem, pattern *regexp.Regexp, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if pattern.MatchString(r.URL.Path) && fileExistsInFS(fs, r.URL.Path) {
+			w = &immutableWriter{ResponseWriter: w}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
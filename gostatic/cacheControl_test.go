@@ -0,0 +1,84 @@
+package gostatic
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestHTMLNoCache(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"index.html":   "<html>index</html>",
+		"page.html":    "<html>page</html>",
+		"app.js":       "js",
+		"style.css":    "css",
+		"headers.json": `{"configs": [{"path": "*", "fileExtension": "*", "headers": [{"key": "Cache-Control", "value": "public, max-age=86400"}]}, {"path": "*", "fileExtension": "css", "headers": [{"key": "Cache-Control", "value": "public, max-age=600"}]}]}`,
+	})
+	s := newTestServer(t, Config{
+		Path:                dir,
+		Fallback:            "/index.html",
+		HeaderConfigPath:    filepath.Join(dir, "headers.json"),
+		DefaultCacheControl: "public, max-age=3600",
+		HTMLNoCache:         true,
+	})
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/", "no-cache, must-revalidate"},
+		{"/page.html", "no-cache, must-revalidate"},
+		{"/missing/route", "no-cache, must-revalidate"},
+		{"/app.js", "public, max-age=86400"},
+		{"/style.css", "public, max-age=600"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if got := serve(s, "GET", tt.target).Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLNoCacheNotModified(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"index.html": "<html>index</html>",
+		"page.html":  "<html>page</html>",
+		"app.js":     "js",
+	})
+	s := newTestServer(t, Config{
+		Path:                dir,
+		EnableETag:          true,
+		DefaultCacheControl: "public, max-age=3600",
+		HTMLNoCache:         true,
+	})
+
+	tests := []struct {
+		target string
+		header string
+		want   string
+	}{
+		{"/", "If-None-Match", "no-cache, must-revalidate"},
+		{"/page.html", "If-None-Match", "no-cache, must-revalidate"},
+		{"/app.js", "If-None-Match", "public, max-age=3600"},
+		{"/", "If-Modified-Since", "no-cache, must-revalidate"},
+		{"/page.html", "If-Modified-Since", "no-cache, must-revalidate"},
+		{"/app.js", "If-Modified-Since", "public, max-age=3600"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header+tt.target, func(t *testing.T) {
+			ok := serve(s, "GET", tt.target)
+			value := ok.Header().Get("ETag")
+			if tt.header == "If-Modified-Since" {
+				value = ok.Header().Get("Last-Modified")
+			}
+			rec := serve(s, "GET", tt.target, tt.header, value)
+			if rec.Code != 304 {
+				t.Fatalf("status %v, want 304", rec.Code)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
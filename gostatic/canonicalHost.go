@@ -0,0 +1,72 @@
+package gostatic
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		return proto
+	}
+	return "http"
+}
+
+// requestHost returns the host the client asked for. With
+// --trust-x-forwarded-host, it is the last X-Forwarded-Host entry set by a
+// trusted proxy, the Host header then being the one of goStatic.
+func (s *Server) requestHost(r *http.Request) string {
+	if s.cfg.TrustXForwardedHost && s.fromTrustedProxy(r) {
+		if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" {
+			entries := strings.Split(forwarded, ",")
+			return strings.TrimSpace(entries[len(entries)-1])
+		}
+	}
+	return r.Host
+}
+
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// isCanonicalHost reports whether host matches the canonical one. When the
+// canonical host has no port, any port on the request host is accepted.
+func isCanonicalHost(host, canonical string) bool {
+	if strings.EqualFold(host, canonical) {
+		return true
+	}
+	if _, _, err := net.SplitHostPort(canonical); err == nil {
+		return false
+	}
+	return strings.EqualFold(stripPort(host), canonical)
+}
+
+// canonicalHostMiddleware redirects requests made to any other host than
+// the canonical one, keeping the scheme, path and query
+func (s *Server) canonicalHostMiddleware(canonical string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isCanonicalHost(s.requestHost(r), canonical) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		s.redirect(w, r, requestScheme(r)+"://"+canonical+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// defaultHostMiddleware sets the host of requests without a Host header,
+// as sent by HTTP/1.0 clients, so redirects keep a valid URL
+func defaultHostMiddleware(host string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "" {
+			r.Host = host
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,115 @@
+package gostatic
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectHost(t *testing.T) {
+	path := writeFiles(t, map[string]string{"a.txt": "a"})
+	tests := []struct {
+		name           string
+		cfg            Config
+		remoteAddr     string
+		forwardedHost  string
+		forwardedProto string
+		wantStatus     int
+		wantLocation   string
+	}{
+		{"canonical host", Config{CanonicalHost: "example.com"}, "10.0.0.1:1234", "", "", http.StatusOK, ""},
+		{"other host", Config{CanonicalHost: "www.example.com"}, "10.0.0.1:1234", "", "", http.StatusMovedPermanently, "http://www.example.com/a.txt?q=1"},
+		{"forwarded host ignored", Config{CanonicalHost: "www.example.com"}, "10.0.0.1:1234", "www.example.com", "", http.StatusMovedPermanently, "http://www.example.com/a.txt?q=1"},
+		{"trusted forwarded host", Config{CanonicalHost: "www.example.com", TrustXForwardedHost: true, TrustedProxies: "10.0.0.1"}, "10.0.0.1:1234", "www.example.com", "", http.StatusOK, ""},
+		{"untrusted forwarded host", Config{CanonicalHost: "www.example.com", TrustXForwardedHost: true, TrustedProxies: "10.0.0.1"}, "10.0.0.2:1234", "www.example.com", "", http.StatusMovedPermanently, "http://www.example.com/a.txt?q=1"},
+		{"https promote", Config{HTTPSPromote: true}, "10.0.0.1:1234", "", "http", http.StatusMovedPermanently, "https://example.com/a.txt?q=1"},
+		{"https promote forwarded host", Config{HTTPSPromote: true, TrustXForwardedHost: true, TrustedProxies: "10.0.0.1"}, "10.0.0.1:1234", "proxy.example.com, www.example.com", "http", http.StatusMovedPermanently, "https://www.example.com/a.txt?q=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Path = path
+			s := newTestServer(t, tt.cfg)
+			r := httptest.NewRequest("GET", "/a.txt?q=1", nil)
+			r.Host = "example.com"
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwardedHost != "" {
+				r.Header.Set("X-Forwarded-Host", tt.forwardedHost)
+			}
+			if tt.forwardedProto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.forwardedProto)
+			}
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, r)
+			if rec.Code != tt.wantStatus || rec.Header().Get("Location") != tt.wantLocation {
+				t.Errorf("got %v %q, want %v %q", rec.Code, rec.Header().Get("Location"), tt.wantStatus, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestTrustXForwardedHostNeedsTrustedProxies(t *testing.T) {
+	if _, err := New(Config{Path: t.TempDir(), TrustXForwardedHost: true}); err == nil {
+		t.Error("New succeeded without --trusted-proxies")
+	}
+}
+
+func TestDefaultHost(t *testing.T) {
+	path := writeFiles(t, map[string]string{"a.txt": "a"})
+	tests := []struct {
+		name         string
+		cfg          Config
+		host         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"canonical default host", Config{DefaultHost: "example.com", CanonicalHost: "example.com"}, "", http.StatusOK, ""},
+		{"other default host", Config{DefaultHost: "example.com", CanonicalHost: "www.example.com"}, "", http.StatusMovedPermanently, "http://www.example.com/a.txt"},
+		{"no host without default host", Config{CanonicalHost: "www.example.com"}, "", http.StatusMovedPermanently, "http://www.example.com/a.txt"},
+		{"https promote", Config{DefaultHost: "example.com", HTTPSPromote: true}, "", http.StatusMovedPermanently, "https://example.com/a.txt"},
+		{"host header kept", Config{DefaultHost: "example.com", HTTPSPromote: true}, "other.example.com", http.StatusMovedPermanently, "https://other.example.com/a.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Path = path
+			s := newTestServer(t, tt.cfg)
+			r := httptest.NewRequest("GET", "/a.txt", nil)
+			r.Proto, r.ProtoMajor, r.ProtoMinor = "HTTP/1.0", 1, 0
+			r.Host = tt.host
+			r.Header.Set("X-Forwarded-Proto", "http")
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, r)
+			if rec.Code != tt.wantStatus || rec.Header().Get("Location") != tt.wantLocation {
+				t.Errorf("got %v %q, want %v %q", rec.Code, rec.Header().Get("Location"), tt.wantStatus, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestDefaultHostHTTP10(t *testing.T) {
+	s := newTestServer(t, Config{
+		Path:          writeFiles(t, map[string]string{"a.txt": "a"}),
+		DefaultHost:   "example.com",
+		CanonicalHost: "www.example.com",
+	})
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// an HTTP/1.0 request, without a Host header
+	fmt.Fprint(conn, "GET /a.txt HTTP/1.0\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != "http://www.example.com/a.txt" {
+		t.Errorf("got %v %q, want %v %q", resp.StatusCode, resp.Header.Get("Location"), http.StatusMovedPermanently, "http://www.example.com/a.txt")
+	}
+}
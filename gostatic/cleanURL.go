@@ -0,0 +1,69 @@
+package gostatic
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// parseCleanURLExtensions parses a comma separated list of extensions,
+// adding the leading dot when missing
+func parseCleanURLExtensions(list string) []string {
+	var extensions []string
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.TrimSpace(ext)
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		extensions = append(extensions, ext)
+	}
+	return extensions
+}
+
+// cleanURLMiddleware serves /about.html for /about, without redirecting,
+// when /about doesn't exist. The extensions are tried in order.
+func cleanURLMiddleware(fs http.FileSystem, extensions []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+		if name != "/" && !strings.HasSuffix(r.URL.Path, "/") {
+			if f, err := fs.Open(name); err == nil {
+				f.Close()
+			} else if os.IsNotExist(err) {
+				for _, ext := range extensions {
+					if fileExistsInFS(fs, name+ext) {
+						r.URL.Path = name + ext
+						r.URL.RawPath = ""
+						break
+					}
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// cleanURLRedirectMiddleware redirects the requests for an existing .html
+// file to the path without extension, e.g. /about.html to /about, so each
+// page has a single URL. The index.html files are already redirected to
+// their directory by the file server.
+func (s *Server) cleanURLRedirectMiddleware(fs http.FileSystem, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+		if strings.HasSuffix(name, ".html") && path.Base(name) != "index.html" && fileExistsInFS(fs, name) {
+			// the request URI keeps the context stripped from the path
+			target, query := r.RequestURI, ""
+			if i := strings.Index(target, "?"); i >= 0 {
+				target, query = target[:i], target[i:]
+			}
+			if strings.HasSuffix(target, ".html") {
+				s.redirect(w, r, strings.TrimSuffix(target, ".html")+query, http.StatusMovedPermanently)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,51 @@
+package gostatic
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCleanURLExtensions(t *testing.T) {
+	s := newTestServer(t, Config{
+		Path: writeFiles(t, map[string]string{
+			"about.html":  "about page",
+			"legacy.htm":  "legacy page",
+			"both.html":   "html first",
+			"both.htm":    "htm second",
+			"exact":       "exact file",
+			"exact.html":  "not this one",
+			"docs/a.html": "nested page",
+			"dir/a.txt":   "a",
+		}),
+		CleanURLExtensions: "html,.htm",
+	})
+
+	tests := []struct {
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/about", http.StatusOK, "about page"},
+		{"/legacy", http.StatusOK, "legacy page"},
+		{"/both", http.StatusOK, "html first"},
+		{"/exact", http.StatusOK, "exact file"},
+		{"/docs/a", http.StatusOK, "nested page"},
+		{"/about.html", http.StatusOK, "about page"},
+		{"/contact", http.StatusNotFound, ""},
+		{"/dir/a", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			rec := serve(s, "GET", tt.target)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if rec.Header().Get("Location") != "" {
+				t.Errorf("got redirected to %v", rec.Header().Get("Location"))
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("got body %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package gostatic
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxCompressedCacheEntry is the largest uncompressed response kept in the cache
+const maxCompressedCacheEntry = 1 << 20
+
+// compressedCache keeps the gzip output of small responses so hot assets
+// are not compressed again on every request. Entries are keyed by path,
+// modification time and size, so a changed file never hits a stale entry.
+type compressedCache struct {
+	entries *boundedCache
+}
+
+// cacheEntry is a compressed response and the time it was cached at
+type cacheEntry struct {
+	data  []byte
+	added time.Time
+}
+
+func newCompressedCache(maxSize int) *compressedCache {
+	return &compressedCache{entries: newBoundedCache(maxSize)}
+}
+
+func (c *compressedCache) get(key string) (cacheEntry, bool) {
+	entry, ok := c.entries.get(key)
+	if !ok {
+		return cacheEntry{}, false
+	}
+	return entry.(cacheEntry), true
+}
+
+func (c *compressedCache) add(key string, data []byte) {
+	c.entries.add(key, cacheEntry{data: data, added: time.Now()}, len(data))
+}
+
+// key returns the cache key of a response, or "" if it can't be cached
+func (c *compressedCache) key(path string, status int, h http.Header) string {
+	if status != http.StatusOK || h.Get("Last-Modified") == "" {
+		return ""
+	}
+	length, err := strconv.Atoi(h.Get("Content-Length"))
+	if err != nil || length > maxCompressedCacheEntry {
+		return ""
+	}
+	return path + "|" + h.Get("Last-Modified") + "|" + strconv.Itoa(length)
+}
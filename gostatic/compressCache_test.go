@@ -0,0 +1,54 @@
+package gostatic
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCacheDebugHeaders(t *testing.T) {
+	content := strings.Repeat("compressible ", 1000)
+	dir := writeFiles(t, map[string]string{"a.txt": content})
+
+	t.Run("enabled", func(t *testing.T) {
+		s := newTestServer(t, Config{Path: dir, CacheCompressed: true, CacheDebugHeaders: true})
+		for i, want := range []string{"MISS", "HIT", "HIT"} {
+			rec := serve(s, "GET", "/a.txt", "Accept-Encoding", "gzip")
+			if got := rec.Header().Get("X-Cache"); got != want {
+				t.Errorf("request %v: X-Cache %q, want %q", i, got, want)
+			}
+			age := rec.Header().Get("Age")
+			if want == "MISS" && age != "" {
+				t.Errorf("request %v: got Age %q on a miss", i, age)
+			}
+			if seconds, err := strconv.Atoi(age); want == "HIT" && (err != nil || seconds < 0) {
+				t.Errorf("request %v: got Age %q, want seconds", i, age)
+			}
+			zr, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body, err := ioutil.ReadAll(zr); err != nil || string(body) != content {
+				t.Errorf("request %v: decompressed body differs from the file", i)
+			}
+		}
+
+		// uncompressed responses aren't cached
+		rec := serve(s, "GET", "/a.txt")
+		if got := rec.Header().Get("X-Cache"); got != "" {
+			t.Errorf("uncompressed response: X-Cache %q, want none", got)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := newTestServer(t, Config{Path: dir, CacheCompressed: true})
+		for i := 0; i < 2; i++ {
+			rec := serve(s, "GET", "/a.txt", "Accept-Encoding", "gzip")
+			if rec.Header().Get("X-Cache") != "" || rec.Header().Get("Age") != "" {
+				t.Errorf("request %v: got X-Cache %q and Age %q, want none", i, rec.Header().Get("X-Cache"), rec.Header().Get("Age"))
+			}
+		}
+	})
+}
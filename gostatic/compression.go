@@ -0,0 +1,402 @@
+package gostatic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// encoder is a compressing writer which can be reused
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// newBrotliWriter returns a brotli encoder. It is only set when built with
+// the brotli tag, keeping the dependency optional.
+var newBrotliWriter func() encoder
+
+// newEncoderPools returns pools of the encoders of each supported content
+// coding
+func newEncoderPools(gzipLevel int, disableBrotli bool) map[string]*sync.Pool {
+	pools := map[string]*sync.Pool{
+		"gzip": {
+			New: func() interface{} {
+				// the level is checked when the server is built
+				w, _ := gzip.NewWriterLevel(ioutil.Discard, gzipLevel)
+				return w
+			},
+		},
+	}
+	if newBrotliWriter != nil && !disableBrotli {
+		pools["br"] = &sync.Pool{
+			New: func() interface{} {
+				return newBrotliWriter()
+			},
+		}
+	}
+	return pools
+}
+
+// parseEncodingPreference parses the --encoding-preference list of content
+// codings
+func parseEncodingPreference(preference string) ([]string, error) {
+	var encodings []string
+	seen := make(map[string]bool)
+	for _, encoding := range strings.Split(preference, ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if _, ok := precompressedExtensions[encoding]; !ok || seen[encoding] {
+			return nil, fmt.Errorf("invalid --encoding-preference %q, the content codings are br and gzip", preference)
+		}
+		seen[encoding] = true
+		encodings = append(encodings, encoding)
+	}
+	return encodings, nil
+}
+
+// supportedEncodings lists the available content codings by preference
+func (s *Server) supportedEncodings() []string {
+	var encodings []string
+	for _, encoding := range s.encodingPreference {
+		if _, ok := s.encoders[encoding]; ok {
+			encodings = append(encodings, encoding)
+		}
+	}
+	return encodings
+}
+
+// compressResponseWriter compresses the response body. Whether to compress is
+// decided once the headers are known: streams (Server-Sent Events), bodies
+// already encoded and responses without body are passed through untouched.
+// With a cache, small responses are compressed once and served from memory.
+// Responses smaller than minSize are sent as is; without a Content-Length,
+// the body is held back until it reaches minSize or ends.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	enc      encoder
+	encoding string
+	// level is the gzip level requested with X-Gzip-Level, if any
+	level        string
+	cache        *compressedCache
+	debugHeaders bool
+	types        *compressibleTypes
+	path         string
+	minSize      int
+	buf          *bytes.Buffer
+	key          string
+	hit          bool
+	passthrough  bool
+	wroteHeader  bool
+	// status and pending hold the response while its size is unknown
+	status  int
+	pending *bytes.Buffer
+}
+
+func (w *compressResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if !compressibleResponse(status, w.Header()) || !w.types.match(w.path, w.mediaType()) {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.minSize > 0 {
+		length, err := strconv.Atoi(w.Header().Get("Content-Length"))
+		if err != nil {
+			w.status = status
+			w.pending = new(bytes.Buffer)
+			return
+		}
+		if length < w.minSize {
+			w.passthrough = true
+			w.ResponseWriter.WriteHeader(status)
+			return
+		}
+	}
+	w.start(status)
+}
+
+func (w *compressResponseWriter) mediaType() string {
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	return mediaType
+}
+
+// start sends the headers of a compressed response
+func (w *compressResponseWriter) start(status int) {
+	w.Header().Set("Content-Encoding", w.encoding)
+	if w.level != "" {
+		w.Header().Set("X-Gzip-Level", w.level)
+	}
+	// the compressed body differs from the file, its ETag can only be weak
+	if etag := w.Header().Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		w.Header().Set("ETag", "W/"+etag)
+	}
+
+	if w.cache != nil {
+		w.key = w.cache.key(w.path+"|"+w.encoding, status, w.Header())
+	}
+	if w.key != "" {
+		if entry, ok := w.cache.get(w.key); ok {
+			w.hit = true
+			if w.debugHeaders {
+				w.Header().Set("X-Cache", "HIT")
+				w.Header().Set("Age", strconv.Itoa(int(time.Since(entry.added).Seconds())))
+			}
+			w.Header().Set("Content-Length", strconv.Itoa(len(entry.data)))
+			w.ResponseWriter.WriteHeader(status)
+			_, _ = w.ResponseWriter.Write(entry.data)
+			return
+		}
+		if w.debugHeaders {
+			w.Header().Set("X-Cache", "MISS")
+		}
+		w.buf = new(bytes.Buffer)
+		w.enc.Reset(w.buf)
+	} else {
+		w.enc.Reset(w.ResponseWriter)
+	}
+
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write makes sure the headers go through WriteHeader first, otherwise the
+// uncompressed Content-Length would be sent along with the compressed
+// stream and the content type would be sniffed from the compressed bytes.
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.hit {
+		return len(b), nil
+	}
+	if w.pending != nil {
+		w.pending.Write(b)
+		if w.pending.Len() < w.minSize {
+			return len(b), nil
+		}
+		if err := w.release(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	return w.enc.Write(b)
+}
+
+// release sends the held back part of a response, compressed or not
+func (w *compressResponseWriter) release(compress bool) error {
+	pending := w.pending
+	w.pending = nil
+	if !compress {
+		w.passthrough = true
+		w.Header().Set("Content-Length", strconv.Itoa(pending.Len()))
+		w.ResponseWriter.WriteHeader(w.status)
+		_, err := w.ResponseWriter.Write(pending.Bytes())
+		return err
+	}
+	w.start(w.status)
+	if w.hit {
+		return nil
+	}
+	_, err := w.enc.Write(pending.Bytes())
+	return err
+}
+
+// Flush sends the data compressed so far to the client
+func (w *compressResponseWriter) Flush() {
+	// the headers decide whether the response is compressed, they must be
+	// sent before the flushed data
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	// a flushed response is a stream, its size doesn't matter
+	if w.pending != nil {
+		_ = w.release(true)
+	}
+	if w.wroteHeader && !w.passthrough && !w.hit && w.buf == nil {
+		_ = w.enc.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close terminates the compressed stream, and stores it in the cache when needed
+func (w *compressResponseWriter) Close() error {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.pending != nil {
+		return w.release(false)
+	}
+	if w.passthrough || w.hit {
+		return nil
+	}
+	if err := w.enc.Close(); err != nil {
+		return err
+	}
+	if w.buf == nil {
+		return nil
+	}
+	w.cache.add(w.key, w.buf.Bytes())
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	return err
+}
+
+// compressibleResponse tells whether a response may be compressed once its
+// status and headers are known
+func compressibleResponse(status int, h http.Header) bool {
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	// compressing breaks the flushing of Server-Sent Events
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	return mediaType != "text/event-stream" && !compressedType(mediaType)
+}
+
+// compressedTypes are the media types already compressed by their format,
+// besides images, audio and video
+var compressedTypes = map[string]bool{
+	"application/gzip":             true,
+	"application/x-gzip":           true,
+	"application/x-bzip2":          true,
+	"application/x-xz":             true,
+	"application/zip":              true,
+	"application/x-7z-compressed":  true,
+	"application/x-rar-compressed": true,
+	"application/zstd":             true,
+	"font/woff":                    true,
+	"font/woff2":                   true,
+}
+
+// compressedType tells whether gzip would gain nothing on a media type
+func compressedType(mediaType string) bool {
+	switch {
+	case mediaType == "image/svg+xml" || mediaType == "image/bmp" || mediaType == "image/x-icon":
+		return false
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "audio/"):
+		return true
+	}
+	return compressedTypes[mediaType]
+}
+
+// responseEncoding returns the content coding to compress the response to r
+// with, or "" when it mustn't be compressed
+func (s *Server) responseEncoding(r *http.Request) string {
+	if s.cfg.DisableCompression || s.cfg.NoCompressSetVary {
+		return ""
+	}
+	// HEAD responses have no body to compress, keep their Content-Length
+	if r.Method == http.MethodHead {
+		return ""
+	}
+	if s.gzipSkipPathRegexp != nil && s.gzipSkipPathRegexp.MatchString(r.URL.Path) {
+		return ""
+	}
+	if s.gzipSkipUARegexp != nil && s.gzipSkipUARegexp.MatchString(r.UserAgent()) {
+		return ""
+	}
+	if s.cfg.NoGzipHTTP2 && r.ProtoMajor == 2 {
+		return ""
+	}
+	return negotiateEncoding(r, s.supportedEncodings())
+}
+
+// compressMiddleware compresses the responses for clients accepting brotli
+// or gzip. The compressed responses of small files are kept in memory with
+// --cache-compressed.
+func (s *Server) compressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := s.responseEncoding(r)
+		if encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cache := s.compressedResponses
+		var enc encoder
+		var overrideLevel string
+		if level, ok := s.gzipLevelOverride(r); ok && encoding == "gzip" && s.cfg.AllowGzipLevelOverride {
+			// the cache only holds responses compressed at --gzip-level
+			enc, _ = gzip.NewWriterLevel(ioutil.Discard, level)
+			cache = nil
+			overrideLevel = strconv.Itoa(level)
+		} else {
+			pool := s.encoders[encoding]
+			enc = pool.Get().(encoder)
+			defer pool.Put(enc)
+		}
+
+		cw := &compressResponseWriter{
+			ResponseWriter: w,
+			enc:            enc,
+			encoding:       encoding,
+			level:          overrideLevel,
+			cache:          cache,
+			debugHeaders:   s.cfg.CacheDebugHeaders,
+			types:          s.gzipTypes,
+			path:           r.URL.Path,
+			minSize:        s.cfg.GzipMinSize,
+		}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks the content coding to use for a response among the
+// supported ones, listed by server preference. The client q-values win, the
+// server order breaks ties. It returns "" when no supported coding is accepted.
+func negotiateEncoding(r *http.Request, supported []string) string {
+	qvalues := make(map[string]float64)
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		pieces := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(pieces[0]))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range pieces[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		qvalues[coding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range supported {
+		q, ok := qvalues[coding]
+		if !ok {
+			q, ok = qvalues["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
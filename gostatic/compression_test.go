@@ -0,0 +1,424 @@
+package gostatic
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		supported      []string
+		want           string
+	}{
+		{"gzip;q=1.0, br;q=0.5", []string{"br", "gzip"}, "gzip"},
+		{"br;q=1.0, gzip;q=0.5", []string{"br", "gzip"}, "br"},
+		{"gzip;q=1.0, br;q=0.5", []string{"gzip", "br"}, "gzip"},
+		{"br;q=1.0, gzip;q=0.5", []string{"gzip", "br"}, "br"},
+		{"gzip, br", []string{"br", "gzip"}, "br"},
+		{"gzip, br", []string{"gzip", "br"}, "gzip"},
+		{"gzip, deflate, br", []string{"gzip"}, "gzip"},
+		{"GZIP", []string{"br", "gzip"}, "gzip"},
+		{"*", []string{"br", "gzip"}, "br"},
+		{"*;q=0.5, gzip", []string{"br", "gzip"}, "gzip"},
+		{"br;q=0, gzip", []string{"br", "gzip"}, "gzip"},
+		{"gzip;q=0", []string{"br", "gzip"}, ""},
+		{"identity", []string{"br", "gzip"}, ""},
+		{"", []string{"br", "gzip"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			if got := negotiateEncoding(r, tt.supported); got != tt.want {
+				t.Errorf("with %v supported: got %q, want %q", tt.supported, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodingPreference(t *testing.T) {
+	tests := []struct {
+		preference string
+		encoders   []string
+		want       []string
+		wantErr    bool
+	}{
+		{"br,gzip", []string{"br", "gzip"}, []string{"br", "gzip"}, false},
+		{"gzip, br", []string{"br", "gzip"}, []string{"gzip", "br"}, false},
+		{"br,gzip", []string{"gzip"}, []string{"gzip"}, false},
+		{"gzip", []string{"br", "gzip"}, []string{"gzip"}, false},
+		{"gzip,deflate", nil, nil, true},
+		{"gzip,gzip", nil, nil, true},
+		{"", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.preference, func(t *testing.T) {
+			preference, err := parseEncodingPreference(tt.preference)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %v, want an error", preference)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := &Server{encoders: make(map[string]*sync.Pool), encodingPreference: preference}
+			for _, encoding := range tt.encoders {
+				s.encoders[encoding] = &sync.Pool{}
+			}
+			got := s.supportedEncodings()
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPrecompressedEncodingPreference(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app.js":    "plain",
+		"app.js.br": "br",
+		"app.js.gz": "gzip",
+	})
+	tests := []struct {
+		preference     string
+		acceptEncoding string
+		want           string
+	}{
+		{"", "gzip, br", "br"},
+		{"gzip,br", "gzip, br", "gzip"},
+		{"gzip,br", "gzip;q=0.5, br", "br"},
+		{"br,gzip", "gzip, br;q=0.5", "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.preference+" "+tt.acceptEncoding, func(t *testing.T) {
+			s := newTestServer(t, Config{
+				Path:               dir,
+				ServePrecompressed: true,
+				EncodingPreference: tt.preference,
+			})
+			rec := serve(s, "GET", "/app.js", "Accept-Encoding", tt.acceptEncoding)
+			if got := rec.Header().Get("Content-Encoding"); got != tt.want || rec.Body.String() != tt.want {
+				t.Errorf("got %q %q, want the %v sibling", got, rec.Body.String(), tt.want)
+			}
+			if got, want := rec.Header().Get("Content-Length"), strconv.Itoa(len(tt.want)); got != want {
+				t.Errorf("Content-Length %v, want %v, the size of the %v sibling", got, want, tt.want)
+			}
+		})
+	}
+}
+
+func TestConcurrentCompression(t *testing.T) {
+	files := make(map[string]string)
+	for i := 0; i < 8; i++ {
+		files[fmt.Sprintf("file%d.txt", i)] = strings.Repeat(fmt.Sprintf("line %d of a compressible file\n", i), 1000*(i+1))
+	}
+	s := newTestServer(t, Config{Path: writeFiles(t, files)})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("file%d.txt", i%len(files))
+			rec := serve(s, "GET", "/"+name, "Accept-Encoding", "gzip")
+			if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+				t.Errorf("%v: Content-Encoding %q, want gzip", name, got)
+				return
+			}
+			zr, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Errorf("%v: %v", name, err)
+				return
+			}
+			body, err := ioutil.ReadAll(zr)
+			if err != nil {
+				t.Errorf("%v: %v", name, err)
+				return
+			}
+			if string(body) != files[name] {
+				t.Errorf("%v: decompressed body differs from the file", name)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestCompressionFlush(t *testing.T) {
+	s := newTestServer(t, Config{Path: writeFiles(t, nil)})
+	events := strings.Repeat("data: event\n\n", 100)
+	tests := []struct {
+		name        string
+		contentType string
+		wantGzip    bool
+	}{
+		{"server-sent events", "text/event-stream", false},
+		{"html", "text/html; charset=utf-8", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := s.compressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				// flushing before writing sends the headers
+				w.(http.Flusher).Flush()
+				for _, event := range strings.SplitAfter(events, "\n\n") {
+					_, _ = io.WriteString(w, event)
+					w.(http.Flusher).Flush()
+				}
+			}))
+			r := httptest.NewRequest("GET", "/events", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			// the headers are the ones sent on the first flush
+			res := rec.Result()
+			if res.StatusCode != http.StatusOK || !rec.Flushed {
+				t.Fatalf("got %v, flushed %v, want a flushed 200", res.StatusCode, rec.Flushed)
+			}
+			body := rec.Body.String()
+			if tt.wantGzip {
+				if got := res.Header.Get("Content-Encoding"); got != "gzip" {
+					t.Fatalf("Content-Encoding: got %q, want gzip", got)
+				}
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := ioutil.ReadAll(zr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = string(data)
+			} else if got := res.Header.Get("Content-Encoding"); got != "" {
+				t.Errorf("Content-Encoding: got %q, want none", got)
+			}
+			if body != events {
+				t.Errorf("got body %q, want the events", body)
+			}
+		})
+	}
+}
+
+func TestCompressionHeadContentLength(t *testing.T) {
+	content := strings.Repeat("compressible ", 1000)
+	s := newTestServer(t, Config{Path: writeFiles(t, map[string]string{"a.txt": content})})
+
+	rec := serve(s, "HEAD", "/a.txt", "Accept-Encoding", "gzip")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %v, want 200", rec.Code)
+	}
+	if got, want := rec.Header().Get("Content-Length"), fmt.Sprint(len(content)); got != want {
+		t.Errorf("Content-Length: got %q, want %q", got, want)
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding: got %q, want none", got)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("got a %v bytes body, want none", rec.Body.Len())
+	}
+	if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Errorf("Vary: got %q, want Accept-Encoding", got)
+	}
+}
+
+func TestPrecompressedHeadContentLength(t *testing.T) {
+	files := map[string]string{
+		"app.js":    strings.Repeat("plain ", 1000),
+		"app.js.br": "brotli sibling",
+		"app.js.gz": "gzip sibling, a little longer",
+	}
+	s := newTestServer(t, Config{Path: writeFiles(t, files), ServePrecompressed: true})
+
+	for _, encoding := range []string{"br", "gzip"} {
+		t.Run(encoding, func(t *testing.T) {
+			rec := serve(s, "HEAD", "/app.js", "Accept-Encoding", encoding)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("got %v, want 200", rec.Code)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != encoding {
+				t.Errorf("Content-Encoding: got %q, want %q", got, encoding)
+			}
+			sibling := files["app.js"+precompressedExtensions[encoding]]
+			if got, want := rec.Header().Get("Content-Length"), fmt.Sprint(len(sibling)); got != want {
+				t.Errorf("Content-Length: got %q, want %q, the size of the sibling", got, want)
+			}
+			if rec.Body.Len() != 0 {
+				t.Errorf("got a %v bytes body, want none", rec.Body.Len())
+			}
+		})
+	}
+}
+
+func TestDefaultGzipTypes(t *testing.T) {
+	content := strings.Repeat("compressible content ", 1000)
+	tests := []struct {
+		name     string
+		compress bool
+	}{
+		{"page.txt", true},
+		{"style.css", true},
+		{"app.js", true},
+		{"data.json", true},
+		{"logo.svg", true},
+		{"photo.png", false},
+		{"photo.jpeg", false},
+		{"archive.zip", false},
+		{"font.woff2", false},
+	}
+	files := make(map[string]string)
+	for _, tt := range tests {
+		files[tt.name] = content
+	}
+	s := newTestServer(t, Config{Path: writeFiles(t, files)})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(s, "GET", "/"+tt.name, "Accept-Encoding", "gzip")
+			if got := rec.Header().Get("Content-Encoding") == "gzip"; got != tt.compress {
+				t.Errorf("compressed: got %v, want %v", got, tt.compress)
+			}
+		})
+	}
+}
+
+func TestGzipSkipPath(t *testing.T) {
+	content := strings.Repeat("compressible ", 1000)
+	s := newTestServer(t, Config{
+		Path: writeFiles(t, map[string]string{
+			"downloads/a.txt": content,
+			"docs/a.txt":      content,
+		}),
+		GzipSkipPath: "^/downloads/",
+	})
+
+	tests := []struct {
+		target   string
+		wantGzip bool
+	}{
+		{"/downloads/a.txt", false},
+		{"/docs/a.txt", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			rec := serve(s, "GET", tt.target, "Accept-Encoding", "gzip")
+			if got := rec.Header().Get("Content-Encoding") == "gzip"; got != tt.wantGzip {
+				t.Errorf("gzipped: got %v, want %v", got, tt.wantGzip)
+			}
+			if !tt.wantGzip && rec.Body.String() != content {
+				t.Errorf("got a body of %v bytes, want the file as is", rec.Body.Len())
+			}
+		})
+	}
+}
+
+func TestGzipSkipUA(t *testing.T) {
+	content := strings.Repeat("compressible ", 1000)
+	s := newTestServer(t, Config{
+		Path:       writeFiles(t, map[string]string{"a.txt": content}),
+		GzipSkipUA: `MSIE [1-6]\.`,
+	})
+
+	tests := []struct {
+		userAgent string
+		wantGzip  bool
+	}{
+		{"Mozilla/4.0 (compatible; MSIE 6.0; Windows NT 5.1)", false},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.userAgent, func(t *testing.T) {
+			rec := serve(s, "GET", "/a.txt", "Accept-Encoding", "gzip", "User-Agent", tt.userAgent)
+			if got := rec.Header().Get("Content-Encoding") == "gzip"; got != tt.wantGzip {
+				t.Errorf("gzipped: got %v, want %v", got, tt.wantGzip)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary: got %q, want Accept-Encoding", got)
+			}
+		})
+	}
+}
+
+func TestCompressionVaryMatrix(t *testing.T) {
+	content := strings.Repeat("compressible ", 1000)
+	dir := writeFiles(t, map[string]string{"a.txt": content})
+	tests := []struct {
+		name               string
+		disableCompression bool
+		noCompressSetVary  bool
+		wantGzip           bool
+		wantVary           bool
+	}{
+		{"compress and vary", false, false, true, true},
+		{"no compression", true, false, false, false},
+		{"vary without compression", false, true, false, true},
+		{"both flags", true, true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, Config{
+				Path:               dir,
+				DisableCompression: tt.disableCompression,
+				NoCompressSetVary:  tt.noCompressSetVary,
+			})
+			rec := serve(s, "GET", "/a.txt", "Accept-Encoding", "gzip")
+			if got := rec.Header().Get("Content-Encoding") == "gzip"; got != tt.wantGzip {
+				t.Errorf("gzipped: got %v, want %v", got, tt.wantGzip)
+			}
+			if !tt.wantGzip && rec.Body.String() != content {
+				t.Errorf("got a body of %v bytes, want the file as is", rec.Body.Len())
+			}
+			if got := rec.Header().Get("Vary") == "Accept-Encoding"; got != tt.wantVary {
+				t.Errorf("Vary: got %q, want it %v", rec.Header().Get("Vary"), tt.wantVary)
+			}
+		})
+	}
+}
+
+func TestNoGzipHTTP2(t *testing.T) {
+	content := strings.Repeat("compressible ", 1000)
+	dir := writeFiles(t, map[string]string{"a.txt": content})
+	tests := []struct {
+		name        string
+		noGzipHTTP2 bool
+		proto       string
+		wantGzip    bool
+	}{
+		{"with the flag", true, "HTTP/2.0", false},
+		{"with the flag", true, "HTTP/1.1", true},
+		{"without the flag", false, "HTTP/2.0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.proto+" "+tt.name, func(t *testing.T) {
+			s := newTestServer(t, Config{Path: dir, NoGzipHTTP2: tt.noGzipHTTP2})
+			r := httptest.NewRequest("GET", "/a.txt", nil)
+			r.Proto = tt.proto
+			r.ProtoMajor, r.ProtoMinor, _ = http.ParseHTTPVersion(tt.proto)
+			r.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, r)
+
+			if got := rec.Header().Get("Content-Encoding") == "gzip"; got != tt.wantGzip {
+				t.Errorf("gzipped: got %v, want %v", got, tt.wantGzip)
+			}
+			if !tt.wantGzip && rec.Body.String() != content {
+				t.Errorf("got a body of %v bytes, want the file as is", rec.Body.Len())
+			}
+		})
+	}
+}
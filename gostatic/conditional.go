@@ -0,0 +1,30 @@
+package gostatic
+
+import (
+	"net/http"
+	"strings"
+)
+
+// requestsNoCache tells whether the client asks for a fresh response, as
+// browsers do on hard refreshes
+func requestsNoCache(r *http.Request) bool {
+	for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+			return true
+		}
+	}
+	return strings.EqualFold(strings.TrimSpace(r.Header.Get("Pragma")), "no-cache")
+}
+
+// noCacheMiddleware drops the conditional headers of requests sent with
+// Cache-Control: no-cache or Pragma: no-cache, so they get a full 200
+// instead of a 304
+func noCacheMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestsNoCache(r) {
+			r.Header.Del("If-None-Match")
+			r.Header.Del("If-Modified-Since")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,49 @@
+package gostatic
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNoCacheRequests(t *testing.T) {
+	s := newTestServer(t, Config{
+		Path: writeFiles(t, map[string]string{
+			"index.html": "<html>index</html>",
+			"a.txt":      "a",
+		}),
+		Fallback:   "/index.html",
+		EnableETag: true,
+	})
+
+	for _, target := range []string{"/", "/index.html", "/a.txt", "/missing"} {
+		first := serve(s, "GET", target)
+		if first.Code != http.StatusOK {
+			t.Fatalf("%v: got %v, want 200", target, first.Code)
+		}
+		etag := first.Header().Get("ETag")
+		lastModified := first.Header().Get("Last-Modified")
+
+		tests := []struct {
+			name    string
+			headers []string
+			want    int
+		}{
+			{"If-None-Match", []string{"If-None-Match", etag}, http.StatusNotModified},
+			{"If-Modified-Since", []string{"If-Modified-Since", lastModified}, http.StatusNotModified},
+			{"Cache-Control no-cache", []string{"If-None-Match", etag, "Cache-Control", "no-cache"}, http.StatusOK},
+			{"Cache-Control max-age=0, no-cache", []string{"If-Modified-Since", lastModified, "Cache-Control", "max-age=0, no-cache"}, http.StatusOK},
+			{"Pragma no-cache", []string{"If-None-Match", etag, "If-Modified-Since", lastModified, "Pragma", "no-cache"}, http.StatusOK},
+		}
+		for _, tt := range tests {
+			t.Run(target+" "+tt.name, func(t *testing.T) {
+				rec := serve(s, "GET", target, tt.headers...)
+				if rec.Code != tt.want {
+					t.Errorf("got %v, want %v", rec.Code, tt.want)
+				}
+				if tt.want == http.StatusOK && rec.Body.String() != first.Body.String() {
+					t.Errorf("got body %q, want %q", rec.Body.String(), first.Body.String())
+				}
+			})
+		}
+	}
+}
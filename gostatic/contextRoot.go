@@ -0,0 +1,54 @@
+package gostatic
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func checkContextRootMode(mode, fallbackPath string) error {
+	switch mode {
+	case "auto", "index", "listing", "404":
+		return nil
+	case "fallback":
+		if fallbackPath == "" {
+			return fmt.Errorf("context root mode fallback needs a fallback file")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown context root mode %q, must be auto, index, fallback, listing or 404", mode)
+}
+
+// contextRootMiddleware decides what the root of the context serves:
+//   - auto: the fallback page when configured, else index.html or a listing
+//   - index: index.html from disk, or a 404 when there is none
+//   - fallback: the fallback page
+//   - listing: index.html from disk, or a directory listing
+//   - 404: always a 404
+func (s *Server) contextRootMiddleware(mode string, fs http.FileSystem, next http.Handler) http.Handler {
+	fileServer := http.FileServer(fs)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "" && r.URL.Path != "/" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch mode {
+		case "index":
+			if !fileExistsInFS(fs, "/index.html") {
+				http.NotFound(w, r)
+				return
+			}
+			r.URL.Path, r.URL.RawPath = "/", ""
+			fileServer.ServeHTTP(w, r)
+		case "listing":
+			r.URL.Path, r.URL.RawPath = "/", ""
+			fileServer.ServeHTTP(w, r)
+		case "fallback":
+			s.serveDefaultPage(w, r)
+		case "404":
+			http.NotFound(w, r)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
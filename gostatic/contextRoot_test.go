@@ -0,0 +1,71 @@
+package gostatic
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestContextRoot(t *testing.T) {
+	withIndex := writeFiles(t, map[string]string{
+		"index.html":    "index page",
+		"fallback.html": "fallback page",
+		"a.txt":         "a",
+	})
+	withoutIndex := writeFiles(t, map[string]string{
+		"fallback.html": "fallback page",
+		"a.txt":         "a",
+	})
+
+	tests := []struct {
+		name       string
+		dir        string
+		mode       string
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"auto with index", withIndex, "auto", "/app/", http.StatusOK, "index page"},
+		{"auto without index", withoutIndex, "auto", "/app/", http.StatusOK, `href="a.txt"`},
+		{"index with index", withIndex, "index", "/app/", http.StatusOK, "index page"},
+		{"index without index", withoutIndex, "index", "/app/", http.StatusNotFound, ""},
+		{"listing with index", withIndex, "listing", "/app/", http.StatusOK, "index page"},
+		{"listing without index", withoutIndex, "listing", "/app/", http.StatusOK, `href="a.txt"`},
+		{"fallback with index", withIndex, "fallback", "/app/", http.StatusOK, "fallback page"},
+		{"fallback without index", withoutIndex, "fallback", "/app/", http.StatusOK, "fallback page"},
+		{"404 with index", withIndex, "404", "/app/", http.StatusNotFound, ""},
+		{"404 without index", withoutIndex, "404", "/app/", http.StatusNotFound, ""},
+		{"file in the context", withoutIndex, "404", "/app/a.txt", http.StatusOK, "a"},
+		{"outside the context", withIndex, "auto", "/a.txt", http.StatusNotFound, ""},
+		{"other context", withIndex, "auto", "/other/a.txt", http.StatusNotFound, ""},
+		{"context prefix", withIndex, "auto", "/application/a.txt", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Path: tt.dir, Context: "app", ContextRoot: tt.mode}
+			if tt.mode == "fallback" {
+				cfg.Fallback = "/fallback.html"
+			}
+			rec := serve(newTestServer(t, cfg), "GET", tt.target)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body %q, want %q in it", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestContextWithoutTrailingSlash(t *testing.T) {
+	for _, files := range []map[string]string{{"index.html": "index page"}, {"a.txt": "a"}} {
+		s := newTestServer(t, Config{Path: writeFiles(t, files), Context: "app"})
+		rec := serve(s, "GET", "/app")
+		if rec.Code != http.StatusMovedPermanently {
+			t.Fatalf("status %v, want %v", rec.Code, http.StatusMovedPermanently)
+		}
+		if got := rec.Header().Get("Location"); got != "/app/" {
+			t.Errorf("Location %q, want /app/", got)
+		}
+	}
+}
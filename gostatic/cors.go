@@ -0,0 +1,52 @@
+package gostatic
+
+import (
+	"net/http"
+	"strings"
+)
+
+// corsAllowedOrigin returns the Access-Control-Allow-Origin value for the
+// request origin, or "" when it isn't allowed. With an allow-list, the
+// matching origin is echoed back.
+func corsAllowedOrigin(origin string, allowed []string) string {
+	for _, o := range allowed {
+		if o == "*" {
+			return "*"
+		}
+		if strings.EqualFold(o, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// corsMiddleware adds the CORS headers to cross-origin requests, and
+// answers preflight requests with a 204 before they reach the files
+func corsMiddleware(origins []string, methods, headers string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		allowOrigin := corsAllowedOrigin(origin, origins)
+		if allowOrigin != "*" {
+			w.Header().Add("Vary", "Origin")
+		}
+		if allowOrigin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+		}
+
+		if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if allowOrigin != "" {
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			if headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,230 @@
+package gostatic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HeaderConfigArray is the array which contains all the custom header rules
+type HeaderConfigArray struct {
+	Configs []HeaderConfig `json:"configs"`
+}
+
+// HeaderConfig is a single header rule specification
+type HeaderConfig struct {
+	Path          string            `json:"path"`
+	FileExtension string            `json:"fileExtension"`
+	Status        []int             `json:"status"`
+	Headers       []HeaderDefiniton `json:"headers"`
+}
+
+// HeaderDefiniton is a key value pair of a specified header rule
+type HeaderDefiniton struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func fileExists(filename string) bool {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return false
+	}
+	return !info.IsDir()
+}
+
+func logHeaderConfig(config HeaderConfig) {
+	fmt.Println("Path: " + config.Path)
+	fmt.Println("FileExtension: " + config.FileExtension)
+	if len(config.Status) > 0 {
+		fmt.Println("Status:", config.Status)
+	}
+
+	for j := 0; j < len(config.Headers); j++ {
+		headerRule := config.Headers[j]
+		fmt.Println(headerRule.Key, ":", headerRule.Value)
+	}
+
+	fmt.Println("------------------------------")
+}
+
+// initHeaderConfig reads the header rules of the config file, when it exists
+func initHeaderConfig(headerConfigPath string) HeaderConfigArray {
+	var headerConfigs HeaderConfigArray
+
+	if fileExists(headerConfigPath) {
+		jsonFile, err := os.Open(headerConfigPath)
+		if err != nil {
+			fmt.Println("Cant't read header config file. Error:")
+			fmt.Println(err)
+		} else {
+			byteValue, _ := ioutil.ReadAll(jsonFile)
+
+			json.Unmarshal(byteValue, &headerConfigs)
+
+			if len(headerConfigs.Configs) > 0 {
+				fmt.Println("Found header config file. Rules:")
+				fmt.Println("------------------------------")
+
+				for i := 0; i < len(headerConfigs.Configs); i++ {
+					configEntry := headerConfigs.Configs[i]
+					logHeaderConfig(configEntry)
+				}
+			} else {
+				fmt.Println("No rules found in header config file.")
+			}
+
+		}
+		jsonFile.Close()
+	}
+
+	return headerConfigs
+}
+
+// statusHeadersWriter applies the header rules scoped to status codes once
+// the status of the response is known
+type statusHeadersWriter struct {
+	http.ResponseWriter
+	rules       []HeaderConfig
+	wroteHeader bool
+}
+
+func (w *statusHeadersWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		for _, rule := range w.rules {
+			if matchStatus(rule, status) {
+				setHeaders(w.Header(), rule.Headers)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusHeadersWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func matchStatus(config HeaderConfig, status int) bool {
+	for _, s := range config.Status {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func setHeaders(header http.Header, headers []HeaderDefiniton) {
+	for j := 0; j < len(headers); j++ {
+		headerEntry := headers[j]
+		header.Set(headerEntry.Key, headerEntry.Value)
+	}
+}
+
+// customHeadersMiddleware applies the header rules matching the request.
+// The default Cache-Control value is set first so any rule overrides it.
+func customHeadersMiddleware(headerConfigs HeaderConfigArray, defaultCacheControl string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if defaultCacheControl != "" {
+			w.Header().Set("Cache-Control", defaultCacheControl)
+		}
+		reqFileExtension := filepath.Ext(r.URL.Path)
+		var statusRules []HeaderConfig
+
+		for i := 0; i < len(headerConfigs.Configs); i++ {
+			configEntry := headerConfigs.Configs[i]
+
+			fileMatch := configEntry.FileExtension == "*" || reqFileExtension == "."+configEntry.FileExtension
+			pathMatch := configEntry.Path == "*" || strings.HasPrefix(r.URL.Path, configEntry.Path)
+
+			if fileMatch && pathMatch {
+				if len(configEntry.Status) > 0 {
+					statusRules = append(statusRules, configEntry)
+				} else {
+					setHeaders(w.Header(), configEntry.Headers)
+				}
+			}
+		}
+
+		if len(statusRules) > 0 {
+			w = &statusHeadersWriter{ResponseWriter: w, rules: statusRules}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// queryHeaderRule sets a header when a query parameter is present
+type queryHeaderRule struct {
+	param  string
+	header HeaderDefiniton
+}
+
+// parseQueryHeaders parses rules specified as param=HeaderName:Value
+func parseQueryHeaders(specs []string) ([]queryHeaderRule, error) {
+	var rules []queryHeaderRule
+	for _, spec := range specs {
+		pieces := strings.SplitN(spec, "=", 2)
+		if len(pieces) != 2 || len(pieces[0]) == 0 {
+			return nil, fmt.Errorf("query header %q must be like param=HeaderName:Value", spec)
+		}
+		key, value := parseHeaderFlag(pieces[1])
+		if len(key) == 0 || len(value) == 0 {
+			return nil, fmt.Errorf("query header %q must be like param=HeaderName:Value", spec)
+		}
+		rules = append(rules, queryHeaderRule{param: pieces[0], header: HeaderDefiniton{Key: key, Value: value}})
+	}
+	return rules, nil
+}
+
+// queryHeadersMiddleware sets the configured headers for requests having
+// the matching query parameter, unless its value is 0 or false. Only the
+// presence of the parameter is used, its value never ends in a header.
+func queryHeadersMiddleware(rules []queryHeaderRule, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		for _, rule := range rules {
+			values, ok := query[rule.param]
+			if !ok || values[0] == "0" || values[0] == "false" {
+				continue
+			}
+			w.Header().Set(rule.header.Key, rule.header.Value)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// dateHeaderMiddleware replaces the Date header set by the server, a nil
+// value removes it
+func dateHeaderMiddleware(value []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = value
+		next.ServeHTTP(w, r)
+	})
+}
+
+// appendHeaderMiddleware sets the header given with --append-header
+func appendHeaderMiddleware(header string, value string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(header, value)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func parseHeaderFlag(headerFlag string) (string, string) {
+	if len(headerFlag) == 0 {
+		return "", ""
+	}
+	pieces := strings.SplitN(headerFlag, ":", 2)
+	if len(pieces) == 1 {
+		return pieces[0], ""
+	}
+	return pieces[0], pieces[1]
+}
@@ -0,0 +1,106 @@
+package gostatic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatusScopedHeaders(t *testing.T) {
+	configs := HeaderConfigArray{Configs: []HeaderConfig{
+		{Path: "*", FileExtension: "*", Headers: []HeaderDefiniton{{Key: "X-Always", Value: "yes"}}},
+		{Path: "*", FileExtension: "*", Status: []int{200}, Headers: []HeaderDefiniton{{Key: "Cache-Control", Value: "max-age=31536000"}}},
+		{Path: "*", FileExtension: "*", Status: []int{502, 503}, Headers: []HeaderDefiniton{{Key: "Retry-After", Value: "120"}}},
+	}}
+
+	tests := []struct {
+		status           int
+		write            bool
+		wantCacheControl string
+		wantRetryAfter   string
+	}{
+		{http.StatusOK, false, "max-age=31536000", ""},
+		// the status is implied by the first write
+		{http.StatusOK, true, "max-age=31536000", ""},
+		{http.StatusServiceUnavailable, false, "no-cache", "120"},
+		{http.StatusBadGateway, false, "no-cache", "120"},
+		{http.StatusNotFound, false, "no-cache", ""},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			h := customHeadersMiddleware(configs, "no-cache", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.write {
+					_, _ = w.Write([]byte("body"))
+					return
+				}
+				w.WriteHeader(tt.status)
+			}))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/a.js", nil))
+
+			res := rec.Result()
+			if res.StatusCode != tt.status {
+				t.Fatalf("got %v, want %v", res.StatusCode, tt.status)
+			}
+			if got := res.Header.Get("X-Always"); got != "yes" {
+				t.Errorf("X-Always: got %q, want yes", got)
+			}
+			if got := res.Header.Get("Cache-Control"); got != tt.wantCacheControl {
+				t.Errorf("Cache-Control: got %q, want %q", got, tt.wantCacheControl)
+			}
+			if got := res.Header.Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After: got %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestStatusScopedHeadersConfigFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.js": "js",
+		"headers.json": `{"configs": [
+			{"path": "*", "fileExtension": "js", "status": [200], "headers": [{"key": "Cache-Control", "value": "max-age=600"}]},
+			{"path": "*", "fileExtension": "*", "status": [404], "headers": [{"key": "X-Missing", "value": "true"}]}
+		]}`,
+	})
+	s := newTestServer(t, Config{Path: dir, HeaderConfigPath: filepath.Join(dir, "headers.json")})
+
+	rec := serve(s, "GET", "/a.js")
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "max-age=600" || rec.Header().Get("X-Missing") != "" {
+		t.Errorf("/a.js: got %v with %v", rec.Code, rec.Header())
+	}
+	rec = serve(s, "GET", "/missing.js")
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Cache-Control") != "" || rec.Header().Get("X-Missing") != "true" {
+		t.Errorf("/missing.js: got %v with %v", rec.Code, rec.Header())
+	}
+}
+
+func TestDefaultCacheControl(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.txt":         "a",
+		"assets/app.js": "js",
+		"headers.json":  `{"configs": [{"path": "/assets/", "fileExtension": "js", "headers": [{"key": "Cache-Control", "value": "public, max-age=31536000"}]}]}`,
+	})
+	s := newTestServer(t, Config{
+		Path:                dir,
+		HeaderConfigPath:    filepath.Join(dir, "headers.json"),
+		DefaultCacheControl: "public, max-age=300",
+	})
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/a.txt", "public, max-age=300"},
+		{"/missing", "public, max-age=300"},
+		{"/assets/app.js", "public, max-age=31536000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if got := serve(s, "GET", tt.target).Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
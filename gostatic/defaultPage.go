@@ -0,0 +1,63 @@
+package gostatic
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+)
+
+func (s *Server) parseFallbackPage(fs http.FileSystem, file string) error {
+	variables := s.cfg.FallbackVariables
+	if len(variables)%2 != 0 {
+		return errors.New("passing variables to be replaced on base file needs to be done by pair var value")
+	}
+
+	data, err := readFileFromFS(fs, file)
+
+	if err != nil {
+		return errors.New("unable to open file " + file)
+	}
+
+	page := string(data)
+
+	for i := len(variables) - 1; i >= 1; i -= 2 {
+		regex := regexp.MustCompile(`'` + variables[i-1] + `' *: *'[^']*'`)
+		if s.cfg.StrictSubstitution && !regex.MatchString(page) {
+			return errors.New("variable " + variables[i-1] + " not found in " + file)
+		}
+		page = regex.ReplaceAllString(page, `'`+variables[i-1]+`':'`+variables[i]+`'`)
+	}
+
+	// the substituted page is only kept in memory, the file on disk is left
+	// untouched so its placeholders can be substituted again next time
+	s.defaultPageBytes = []byte(page)
+	return nil
+}
+
+func (s *Server) serveDefaultPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html") // clarify return type (MIME)
+	if version := s.versionETag(); version != "" {
+		w.Header().Set("ETag", version)
+	} else if s.cfg.EnableETag {
+		w.Header().Set("ETag", contentETag(s.defaultPageBytes))
+	}
+	// ServeContent sends the Content-Length and handles conditional requests
+	http.ServeContent(w, r, "", startTime, bytes.NewReader(s.defaultPageBytes))
+}
+
+func (s *Server) defaultPage(fallbackPath string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// the path is relative once the context is stripped
+		requestPath := path.Clean("/" + r.URL.Path)
+		if requestPath == "/" || requestPath == fallbackPath {
+			log.Println("Passing here " + r.URL.RequestURI())
+			s.serveDefaultPage(w, r)
+		} else {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
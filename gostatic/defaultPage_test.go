@@ -0,0 +1,75 @@
+package gostatic
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDefaultPageContentLength(t *testing.T) {
+	// larger than the response buffer, whose size Go would send anyway
+	page := "<script>var config = {'API_URL':'placeholder'};</script>" + strings.Repeat("<p>content</p>", 1000)
+	s := newTestServer(t, Config{
+		Path:              writeFiles(t, map[string]string{"index.html": page}),
+		Fallback:          "/index.html",
+		FallbackVariables: []string{"API_URL", "https://api.example.com"},
+	})
+	want := strings.Replace(page, "placeholder", "https://api.example.com", 1)
+
+	for _, method := range []string{"GET", "HEAD"} {
+		for _, target := range []string{"/", "/index.html", "/missing/route"} {
+			t.Run(method+" "+target, func(t *testing.T) {
+				rec := serve(s, method, target)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("got %v, want 200", rec.Code)
+				}
+				if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(len(want)) {
+					t.Errorf("Content-Length: got %q, want %v", got, len(want))
+				}
+				if method == "GET" && rec.Body.String() != want {
+					t.Errorf("got a body of %v bytes, want the substituted page", rec.Body.Len())
+				}
+			})
+		}
+	}
+}
+
+func TestStrictSubstitution(t *testing.T) {
+	page := "<script>var config = {'API_URL':'placeholder'};</script>"
+	dir := writeFiles(t, map[string]string{"index.html": page})
+	tests := []struct {
+		name     string
+		variable string
+		strict   bool
+		wantErr  bool
+		wantPage string
+	}{
+		{"present key, lenient", "API_URL", false, false, "<script>var config = {'API_URL':'https://api.example.com'};</script>"},
+		{"present key, strict", "API_URL", true, false, "<script>var config = {'API_URL':'https://api.example.com'};</script>"},
+		{"missing key, lenient", "API_ULR", false, false, page},
+		{"missing key, strict", "API_ULR", true, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(Config{
+				Path:               dir,
+				Fallback:           "/index.html",
+				FallbackVariables:  []string{tt.variable, "https://api.example.com"},
+				StrictSubstitution: tt.strict,
+			})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.variable) {
+					t.Errorf("got error %v, want one naming %v", err, tt.variable)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rec := serve(s, "GET", "/"); rec.Body.String() != tt.wantPage {
+				t.Errorf("got %q, want %q", rec.Body.String(), tt.wantPage)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package gostatic
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// noListingFS hides the directories without an index.html, so
+// http.FileServer answers 404, or the fallback is served, instead of a
+// listing of their files
+type noListingFS struct {
+	fs http.FileSystem
+}
+
+func (n noListingFS) Open(name string) (http.File, error) {
+	f, err := n.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() && !fileExistsInFS(n.fs, path.Join(name, "index.html")) {
+		f.Close()
+		return nil, os.ErrNotExist
+	}
+	return f, nil
+}
+
+// isListing tells whether http.FileServer answers name with a directory
+// listing, that is a directory without index.html
+func isListing(fs http.FileSystem, name string) bool {
+	f, err := fs.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	return err == nil && info.IsDir() && !fileExistsInFS(fs, path.Join(name, "index.html"))
+}
+
+// listingCompressMiddleware serves the directory listings with compressed,
+// the handler compressing next, for --gzip-listings-only. The files are
+// served as is.
+func listingCompressMiddleware(fs http.FileSystem, compressed, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") && isListing(fs, path.Clean("/"+r.URL.Path)) {
+			compressed.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,60 @@
+package gostatic
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestGzipListingsOnly(t *testing.T) {
+	s := newTestServer(t, Config{
+		Path: writeFiles(t, map[string]string{
+			"docs/a.txt":      strings.Repeat("a", 1024),
+			"app.js":          "plain",
+			"app.js.gz":       "precompressed",
+			"site/index.html": "index",
+		}),
+		GzipListingsOnly:   true,
+		ServePrecompressed: true,
+	})
+
+	t.Run("listing", func(t *testing.T) {
+		rec := serve(s, "GET", "/docs/", "Accept-Encoding", "gzip")
+		if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("Content-Encoding: got %q, want gzip", got)
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), "a.txt") {
+			t.Errorf("listing %q doesn't link a.txt", body)
+		}
+	})
+
+	for _, target := range []string{"/docs/a.txt", "/site/"} {
+		t.Run(target, func(t *testing.T) {
+			rec := serve(s, "GET", target, "Accept-Encoding", "gzip")
+			if got := rec.Header().Get("Content-Encoding"); got != "" {
+				t.Errorf("Content-Encoding: got %q, want none", got)
+			}
+		})
+	}
+
+	t.Run("precompressed", func(t *testing.T) {
+		for _, accept := range []string{"gzip", ""} {
+			rec := serve(s, "GET", "/app.js", "Accept-Encoding", accept)
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Accept-Encoding %q: got Vary %q, want Accept-Encoding", accept, got)
+			}
+		}
+		if rec := serve(s, "GET", "/app.js", "Accept-Encoding", "gzip"); rec.Body.String() != "precompressed" {
+			t.Errorf("got body %q, want the .gz sibling", rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,22 @@
+package gostatic
+
+import (
+	"net/http"
+	"regexp"
+)
+
+// encodedSequenceRegexp matches a percent-encoded byte
+var encodedSequenceRegexp = regexp.MustCompile(`%[0-9A-Fa-f]{2}`)
+
+// rejectDoubleEncodingMiddleware answers 400 to requests whose path still
+// holds percent-encoded bytes once decoded, like %252e%252e for a
+// traversal, so a second decoding can never reveal them
+func rejectDoubleEncodingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if encodedSequenceRegexp.MatchString(r.URL.Path) {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,45 @@
+package gostatic
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRejectDoubleEncoding(t *testing.T) {
+	files := map[string]string{
+		"a.txt":     "a",
+		"a b.txt":   "a b",
+		"100%.txt":  "percent",
+		"dir/b.txt": "b",
+	}
+	s := newTestServer(t, Config{Path: writeFiles(t, files), RejectDoubleEncoding: true})
+	lenient := newTestServer(t, Config{Path: writeFiles(t, files)})
+
+	tests := []struct {
+		name        string
+		target      string
+		wantStatus  int
+		wantLenient int
+	}{
+		{"plain", "/a.txt", http.StatusOK, http.StatusOK},
+		{"single encoding", "/a%20b.txt", http.StatusOK, http.StatusOK},
+		{"encoded percent sign", "/100%25.txt", http.StatusOK, http.StatusOK},
+		// decoded once, the path is cleaned with a redirect
+		{"single encoded traversal", "/dir/%2e%2e/a.txt", http.StatusMovedPermanently, http.StatusMovedPermanently},
+		{"double encoded traversal", "/%252e%252e/%252e%252e/etc/passwd", http.StatusBadRequest, http.StatusNotFound},
+		{"double encoded slash", "/dir%252f..%252fa.txt", http.StatusBadRequest, http.StatusNotFound},
+		{"double encoded file", "/a%2520b.txt", http.StatusBadRequest, http.StatusNotFound},
+		{"triple encoded traversal", "/%25252e%25252e/%25252e%25252e/etc/passwd", http.StatusBadRequest, http.StatusNotFound},
+		{"mixed case", "/%252E%252e/a.txt", http.StatusBadRequest, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(s, "GET", tt.target); rec.Code != tt.wantStatus {
+				t.Errorf("got %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if rec := serve(lenient, "GET", tt.target); rec.Code != tt.wantLenient {
+				t.Errorf("without --reject-double-encoding: got %v, want %v", rec.Code, tt.wantLenient)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package gostatic
+
+import (
+	"net/http"
+)
+
+func isEmptyDir(fs http.FileSystem, name string) bool {
+	f, err := fs.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	entries, err := f.Readdir(1)
+	return err != nil && len(entries) == 0
+}
+
+// emptyRootMiddleware answers requests to the root with message while the
+// static files path is empty, telling "working but empty" from "broken"
+func emptyRootMiddleware(fs http.FileSystem, message string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.URL.Path == "/" || r.URL.Path == "") && isEmptyDir(fs, "/") {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte(message + "\n"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
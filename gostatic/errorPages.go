@@ -0,0 +1,64 @@
+package gostatic
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// loadErrorPage reads a custom error page from the static files
+func loadErrorPage(fs http.FileSystem, page string) ([]byte, error) {
+	data, err := readFileFromFS(fs, page)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open error page %v: %v", page, err)
+	}
+	return data, nil
+}
+
+// errorPageWriter replaces the body of responses having the given status
+// with a custom page
+type errorPageWriter struct {
+	http.ResponseWriter
+	status      int
+	page        []byte
+	intercepted bool
+}
+
+func (w *errorPageWriter) WriteHeader(status int) {
+	if status != w.status {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.intercepted = true
+	w.Header().Del("X-Content-Type-Options")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(w.page)))
+	w.ResponseWriter.WriteHeader(status)
+	_, _ = w.ResponseWriter.Write(w.page)
+}
+
+func (w *errorPageWriter) Write(b []byte) (int, error) {
+	if w.intercepted {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// errorPageMiddleware serves page instead of the default body of responses
+// with the given status
+func errorPageMiddleware(status int, page []byte, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&errorPageWriter{ResponseWriter: w, status: status, page: page}, r)
+	})
+}
+
+// readFileFromFS returns the content of a file of fs
+func readFileFromFS(fs http.FileSystem, name string) ([]byte, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
@@ -0,0 +1,64 @@
+package gostatic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// contentETag is a strong ETag computed from the content itself
+func contentETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// fileETag computes the ETag of the file http.FileServer would serve for
+// name, from its modification time and size. It returns "" when there is
+// no such file.
+func fileETag(fs http.FileSystem, name string) string {
+	f, err := fs.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	if mf, ok := f.(memoryFile); ok {
+		return mf.etag
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return ""
+	}
+	if info.IsDir() {
+		if strings.HasSuffix(name, "/index.html") {
+			return ""
+		}
+		return fileETag(fs, path.Join(name, "index.html"))
+	}
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
+
+// versionETag is the ETag of every file with --etag-version, "" otherwise
+func (s *Server) versionETag() string {
+	if s.cfg.ETagVersion == "" {
+		return ""
+	}
+	return `"` + s.cfg.ETagVersion + `"`
+}
+
+// etagMiddleware sets the ETag of the served file, letting http.FileServer
+// answer 304 Not Modified to requests with a matching If-None-Match. With
+// --etag-version, all the files share the ETag of the deploy.
+func (s *Server) etagMiddleware(fs http.FileSystem, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if etag := fileETag(fs, path.Clean("/"+r.URL.Path)); etag != "" {
+			if version := s.versionETag(); version != "" {
+				etag = version
+			}
+			w.Header().Set("ETag", etag)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
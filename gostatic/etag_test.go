@@ -0,0 +1,49 @@
+package gostatic
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestETagVersion(t *testing.T) {
+	files := map[string]string{
+		"index.html": "<html>index</html>",
+		"a.txt":      "a",
+		"css/b.css":  "b",
+	}
+	dir := writeFiles(t, files)
+	s := newTestServer(t, Config{Path: dir, Fallback: "/index.html", ETagVersion: "v1"})
+	next := newTestServer(t, Config{Path: dir, Fallback: "/index.html", ETagVersion: "v2"})
+
+	for _, target := range []string{"/", "/a.txt", "/css/b.css", "/missing/route"} {
+		t.Run(target, func(t *testing.T) {
+			rec := serve(s, "GET", target)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("got %v, want 200", rec.Code)
+			}
+			if got := rec.Header().Get("ETag"); got != `"v1"` {
+				t.Errorf("ETag: got %v, want \"v1\"", got)
+			}
+
+			if rec := serve(s, "GET", target, "If-None-Match", `"v1"`); rec.Code != http.StatusNotModified {
+				t.Errorf("same version: got %v, want 304", rec.Code)
+			}
+			if rec := serve(s, "GET", target, "If-None-Match", `"v0"`); rec.Code != http.StatusOK {
+				t.Errorf("previous version: got %v, want 200", rec.Code)
+			}
+			// the next deploy invalidates every file at once
+			rec = serve(next, "GET", target, "If-None-Match", `"v1"`)
+			if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"v2"` {
+				t.Errorf("next deploy: got %v with ETag %v, want 200 with \"v2\"", rec.Code, rec.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestInvalidETagVersion(t *testing.T) {
+	for _, version := range []string{`v"1`, "v 1"} {
+		if _, err := New(Config{Path: writeFiles(t, nil), ETagVersion: version}); err == nil {
+			t.Errorf("%q: got no error", version)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package gostatic_test
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/PierreZ/goStatic/gostatic"
+)
+
+//go:embed testdata/site
+var site embed.FS
+
+// The files can be compiled into the binary with embed, leaving nothing
+// on disk to deploy
+func ExampleConfig_fileSystem() {
+	files, err := fs.Sub(site, "testdata/site")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	server, err := gostatic.New(gostatic.Config{
+		FileSystem: http.FS(files),
+		Fallback:   "/index.html",
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/hello.txt", nil))
+	fmt.Print(rec.Code, " ", rec.Body.String())
+	// Output: 200 Hello from the binary
+}
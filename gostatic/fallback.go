@@ -0,0 +1,106 @@
+package gostatic
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// fallback opens defaultPath when the underlying fs returns os.ErrNotExist
+type fallback struct {
+	defaultPath string
+	fs          http.FileSystem
+	// defaultContent, when set, is served in place of the defaultPath file
+	// content, so the page is the same whichever way it is requested
+	defaultContent []byte
+	// prefixes, when set, restricts the fallback to the paths under them
+	prefixes []string
+}
+
+func (fb fallback) inPrefixes(requestPath string) bool {
+	if len(fb.prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range fb.prefixes {
+		if strings.HasPrefix(requestPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryFile is a file whose content is held in memory
+type memoryFile struct {
+	*bytes.Reader
+	info os.FileInfo
+	etag string
+}
+
+func (f memoryFile) Close() error {
+	return nil
+}
+
+func (f memoryFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (f memoryFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+type memoryFileInfo struct {
+	os.FileInfo
+	size int64
+}
+
+func (fi memoryFileInfo) Size() int64 {
+	return fi.size
+}
+
+func OpenDefault(fb fallback, requestPath string) (http.File, error) {
+	requestPath = path.Dir(requestPath)
+	defaultFile := requestPath + "/" + fb.defaultPath
+
+	f, err := fb.fs.Open(defaultFile)
+	if os.IsNotExist(err) && requestPath != "" {
+		parentPath, _ := path.Split(requestPath)
+		return OpenDefault(fb, parentPath)
+	}
+	return f, err
+}
+
+// openDefaultPath opens the absolute defaultPath, from memory when possible
+func (fb fallback) openDefaultPath() (http.File, error) {
+	f, err := fb.fs.Open(fb.defaultPath)
+	if err != nil || fb.defaultContent == nil {
+		return f, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return memoryFile{
+		Reader: bytes.NewReader(fb.defaultContent),
+		info:   memoryFileInfo{FileInfo: info, size: int64(len(fb.defaultContent))},
+		etag:   contentETag(fb.defaultContent),
+	}, nil
+}
+
+func (fb fallback) Open(requestPath string) (http.File, error) {
+	absolute := len(fb.defaultPath) == 0 || fb.defaultPath[0] == '/'
+	if absolute && path.Clean("/"+requestPath) == fb.defaultPath {
+		return fb.openDefaultPath()
+	}
+
+	f, err := fb.fs.Open(requestPath)
+	if os.IsNotExist(err) && fb.inPrefixes(requestPath) {
+		if absolute {
+			return fb.openDefaultPath()
+		}
+		return OpenDefault(fb, requestPath)
+	}
+	return f, err
+}
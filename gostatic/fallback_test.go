@@ -0,0 +1,51 @@
+package gostatic
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestFallbackPrefixes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"index.html":         "fallback page",
+		"app/existing.txt":   "app file",
+		"other/existing.txt": "other file",
+	})
+
+	tests := []struct {
+		name       string
+		prefixes   []string
+		context    string
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"missing in a prefix", []string{"/app/", "/docs/"}, "", "/app/missing", http.StatusOK, "fallback page"},
+		{"missing deep in a prefix", []string{"/app/", "/docs/"}, "", "/docs/deep/missing.html", http.StatusOK, "fallback page"},
+		{"existing in a prefix", []string{"/app/", "/docs/"}, "", "/app/existing.txt", http.StatusOK, "app file"},
+		{"missing out of the prefixes", []string{"/app/", "/docs/"}, "", "/other/missing", http.StatusNotFound, ""},
+		{"missing at the root", []string{"/app/", "/docs/"}, "", "/missing", http.StatusNotFound, ""},
+		{"existing out of the prefixes", []string{"/app/", "/docs/"}, "", "/other/existing.txt", http.StatusOK, "other file"},
+		{"sharing the start of a prefix", []string{"/app/"}, "", "/application/missing", http.StatusNotFound, ""},
+		{"no prefixes", nil, "", "/other/missing", http.StatusOK, "fallback page"},
+		{"missing in a prefix with a context", []string{"/app/"}, "site", "/site/app/missing", http.StatusOK, "fallback page"},
+		{"missing out of the prefixes with a context", []string{"/app/"}, "site", "/site/other/missing", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, Config{
+				Path:             dir,
+				Context:          tt.context,
+				Fallback:         "/index.html",
+				FallbackPrefixes: tt.prefixes,
+			})
+			rec := serve(s, "GET", tt.target)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
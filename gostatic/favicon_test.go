@@ -0,0 +1,42 @@
+package gostatic
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func TestDefaultFavicon(t *testing.T) {
+	tests := []struct {
+		name             string
+		files            map[string]string
+		defaultIcon      bool
+		wantStatus       int
+		wantBody         []byte
+		wantCacheControl string
+	}{
+		{"absent", nil, true, http.StatusOK, defaultFavicon, "public, max-age=86400"},
+		{"present", map[string]string{"favicon.ico": "real icon"}, true, http.StatusOK, []byte("real icon"), ""},
+		{"absent without the flag", nil, false, http.StatusNotFound, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, Config{Path: writeFiles(t, tt.files), DefaultFavicon: tt.defaultIcon})
+			rec := serve(s, "GET", "/favicon.ico")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != nil && !bytes.Equal(rec.Body.Bytes(), tt.wantBody) {
+				t.Errorf("got body %q, want %q", rec.Body.Bytes(), tt.wantBody)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.wantCacheControl {
+				t.Errorf("Cache-Control: got %q, want %q", got, tt.wantCacheControl)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if got := rec.Header().Get("Content-Type"); got != "image/x-icon" && got != "image/vnd.microsoft.icon" {
+					t.Errorf("Content-Type: got %q, want an icon", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package gostatic
+
+import (
+	"errors"
+	"net"
+	"net/http"
+)
+
+// geoIPOpener opens a GeoIP database and returns its lookup function. It is
+// only set when built with the geoip tag, keeping the dependency optional.
+var geoIPOpener func(path string) (func(ip net.IP) (country, city string), error)
+
+// openGeoIP opens the database enriching the request logs with the client
+// location
+func openGeoIP(path string) (func(ip net.IP) (country, city string), error) {
+	if geoIPOpener == nil {
+		return nil, errors.New("geoip support is not built in, build with -tags geoip")
+	}
+	return geoIPOpener(path)
+}
+
+func (s *Server) clientLocation(r *http.Request) (country, city string) {
+	if s.geoIPLookup == nil {
+		return "", ""
+	}
+	ip := net.ParseIP(stripPort(r.RemoteAddr))
+	if ip == nil {
+		return "", ""
+	}
+	return s.geoIPLookup(ip)
+}
@@ -0,0 +1,26 @@
+//go:build geoip
+// +build geoip
+
+package gostatic
+
+import (
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+func init() {
+	geoIPOpener = func(path string) (func(ip net.IP) (string, string), error) {
+		db, err := geoip2.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return func(ip net.IP) (string, string) {
+			record, err := db.City(ip)
+			if err != nil {
+				return "", ""
+			}
+			return record.Country.IsoCode, record.City.Names["en"]
+		}, nil
+	}
+}
@@ -0,0 +1,773 @@
+// Package gostatic serves static files, with everything the goStatic
+// command offers: fallback page, basic auth, custom headers, compression,
+// health checks and more. Each option of the command is a field of Config.
+package gostatic
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config is the configuration of a Server. Its fields are the options of
+// the goStatic command, the zero values of those without a meaningful
+// zero value standing for the command defaults.
+type Config struct {
+	// Path is the directory of the static files
+	Path string
+	// FileSystem serves the static files instead of Path when set, e.g.
+	// http.FS of an embed.FS
+	FileSystem http.FileSystem
+	// Context is the path the files are served at, e.g. doc for /doc/
+	Context string
+	// ContextRoot is what the root of the context serves, either auto,
+	// the default, index, fallback, listing or 404
+	ContextRoot string
+	// Origin fetches the files from this upstream URL instead of Path
+	Origin string
+	// OriginCacheTTL overrides the upstream cache headers of the origin
+	OriginCacheTTL time.Duration
+	// StaleWhileRevalidate is how long an expired file from the origin is
+	// still served while it is fetched again
+	StaleWhileRevalidate time.Duration
+
+	// Fallback is the page served for missing files, "" to disable it
+	Fallback string
+	// FallbackPrefixes restrict the fallback to these path prefixes
+	FallbackPrefixes []string
+	// FallbackVariables are pairs of variable name and value replaced in
+	// the fallback page
+	FallbackVariables []string
+	// FailOnMissingFallback makes New fail when the fallback page doesn't
+	// exist, instead of disabling the fallback
+	FailOnMissingFallback bool
+	// StrictSubstitution makes New fail when a variable isn't found in the
+	// fallback page
+	StrictSubstitution bool
+	// SPA serves the fallback page with a 200 for every missing path
+	SPA bool
+	// CleanURLs serves /about.html for /about, and redirects /about.html
+	CleanURLs bool
+	// CleanURLExtensions are the comma separated extensions tried for
+	// missing files
+	CleanURLExtensions string
+	// DisableDirectoryListing hides the directories without an index.html
+	DisableDirectoryListing bool
+	// MaxFileSize refuses the larger files with a 403, 0 means no limit
+	MaxFileSize int64
+	// RespectSaveData serves the low quality variants to Save-Data clients
+	RespectSaveData bool
+	// DefaultFavicon serves a transparent /favicon.ico when there is none
+	DefaultFavicon bool
+	// EmptyRootMessage is served at the root while there are no files
+	EmptyRootMessage string
+	// EnableManifest serves a JSON manifest of the files at ManifestPath,
+	// /_manifest.json by default, listing the ManifestFields, size and
+	// hash by default
+	EnableManifest bool
+	ManifestPath   string
+	ManifestFields string
+	// InjectSRI adds Subresource Integrity hashes to the HTML pages
+	InjectSRI bool
+	// ServeStale keeps small files in memory, up to ServeStaleSize bytes,
+	// 64MB by default, to serve them while the disk fails, for up to
+	// ServeStaleMaxAge, 1h by default, after they were last opened
+	ServeStale       bool
+	ServeStaleSize   int
+	ServeStaleMaxAge time.Duration
+	// NotFoundPage and ForbiddenPage are custom pages for 404 and 403
+	NotFoundPage  string
+	ForbiddenPage string
+	// RejectDoubleEncoding answers 400 to paths still percent-encoded
+	RejectDoubleEncoding bool
+
+	// AppendHeader is a header added to all responses, as Name:Value
+	AppendHeader string
+	// HeaderConfigPath is the JSON file of custom header rules
+	HeaderConfigPath string
+	// DefaultCacheControl is the Cache-Control of responses without one
+	DefaultCacheControl string
+	// HTMLNoCache forces Cache-Control: no-cache on HTML pages
+	HTMLNoCache bool
+	// ImmutablePattern matches the paths served as immutable
+	ImmutablePattern string
+	// QueryHeaders are headers set by query parameter, as param=Name:Value
+	QueryHeaders []string
+	// DateHeader is auto, the default, or off. FixedDate, when set, is
+	// sent as the Date of every response.
+	DateHeader string
+	FixedDate  string
+	// EnableETag sends an ETag with the files. ETagVersion, when set, is
+	// the ETag of all of them.
+	EnableETag  bool
+	ETagVersion string
+	// EnableCORS sends CORS headers to CORSAllowOrigin, * by default, and
+	// answers the preflight requests
+	EnableCORS       bool
+	CORSAllowOrigin  string
+	CORSAllowMethods string
+	CORSAllowHeaders string
+
+	// DisableCompression never compresses responses. NoCompressSetVary
+	// neither does, but still sends Vary: Accept-Encoding.
+	DisableCompression bool
+	NoCompressSetVary  bool
+	// DisableBrotli only compresses with gzip
+	DisableBrotli bool
+	// EncodingPreference is the comma separated content codings, most
+	// preferred first, picked between those the client accepts with the
+	// same quality value. br,gzip when empty.
+	EncodingPreference string
+	// ServePrecompressed serves the .br and .gz siblings of the files
+	ServePrecompressed bool
+	// GzipLevel is the gzip level, 6 by default
+	GzipLevel int
+	// GzipTypes are the comma separated extensions compressed, * for all,
+	// DefaultGzipTypes when empty
+	GzipTypes string
+	// GzipMinSize is the size below which responses aren't compressed
+	GzipMinSize int
+	// GzipSkipPath and GzipSkipUA are regular expressions of the paths and
+	// User-Agents never compressed
+	GzipSkipPath string
+	GzipSkipUA   string
+	// GzipListingsOnly only compresses the directory listings
+	GzipListingsOnly bool
+	// NoGzipHTTP2 doesn't compress the responses to HTTP/2 requests
+	NoGzipHTTP2 bool
+	// AllowGzipLevelOverride lets the clients of GzipLevelOverrideFrom,
+	// the loopback addresses by default, pick the gzip level
+	AllowGzipLevelOverride bool
+	GzipLevelOverrideFrom  string
+	// CacheCompressed keeps the compressed small files in memory, up to
+	// CacheCompressedSize bytes, 16MB by default
+	CacheCompressed     bool
+	CacheCompressedSize int
+	// CacheDebugHeaders sends X-Cache and Age with cacheable responses
+	CacheDebugHeaders bool
+
+	// BasicAuth enables basic auth, with the user:password of
+	// BasicAuthCredentials, the users of BasicAuthFile, or else the
+	// DefaultUser, gopher by default, with a random password of
+	// PasswordLength bytes, 16 by default
+	BasicAuth            bool
+	BasicAuthCredentials string
+	BasicAuthFile        string
+	DefaultUser          string
+	PasswordLength       int
+	// AuthPaths restrict basic auth to these path prefixes
+	AuthPaths []string
+
+	// HTTPSPromote redirects the requests forwarded over HTTP to HTTPS
+	HTTPSPromote bool
+	// CanonicalHost redirects the requests to other hosts to this one
+	CanonicalHost string
+	// DefaultHost is the host of requests without a Host header
+	DefaultHost string
+	// RedirectBody sends a minimal HTML page with the redirects
+	RedirectBody bool
+	// RedirectsConfig is the JSON file of redirect rules
+	RedirectsConfig string
+	// TrustXForwardedHost and TrustForwardedFor trust the X-Forwarded-Host
+	// and X-Forwarded-For headers set by the TrustedProxies, comma
+	// separated IPs or CIDRs
+	TrustXForwardedHost bool
+	TrustForwardedFor   bool
+	TrustedProxies      string
+	// AllowIPs and DenyIPs are the networks of the clients served or
+	// refused with a 403
+	AllowIPs []string
+	DenyIPs  []string
+
+	// EnableHealth serves a health check at HealthPath, /health by
+	// default, answering HealthBody, Ok by default
+	EnableHealth bool
+	HealthPath   string
+	HealthBody   string
+	// HealthMaxInFlight fails the health check above this many requests
+	HealthMaxInFlight int
+	// HealthOnly serves no files, only the health check
+	HealthOnly bool
+	// PrestopGrace enables /admin/prestop, waiting this long
+	PrestopGrace time.Duration
+
+	// EnableLogging logs the requests in LogFormat, text by default, with
+	// the LogFields of structured formats
+	EnableLogging bool
+	LogFormat     string
+	LogFields     string
+	// LogHeaders logs the request and response headers
+	LogHeaders bool
+	// AccessLog receives the request logs having their own timestamp,
+	// os.Stderr by default
+	AccessLog io.Writer
+	// GeoIPDB is a MaxMind database locating the clients in the logs
+	GeoIPDB string
+	// VerboseStartup logs the middleware chain
+	VerboseStartup bool
+
+	// Ports are the ports to listen on
+	Ports []int
+	// UnixSocket is listened on instead of the ports when set
+	UnixSocket string
+	// Cert and Key serve HTTPS
+	Cert string
+	Key  string
+	// DisableHTTP2 only serves HTTP/1.1 over TLS
+	DisableHTTP2 bool
+	// HTTPRedirectPort redirects plain HTTP to HTTPS
+	HTTPRedirectPort int
+	// TLSTicketRotation is the interval of the session ticket keys
+	TLSTicketRotation time.Duration
+	// TCPKeepAlive is the keep-alive period, 0 disables it
+	TCPKeepAlive time.Duration
+	// ReadTimeout, WriteTimeout and IdleTimeout bound the connections, 0
+	// means no limit
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// DrainTimeout is how long to serve with a failing health check on
+	// shutdown, ShutdownTimeout how long requests then have to complete,
+	// 10s when 0
+	DrainTimeout    time.Duration
+	ShutdownTimeout time.Duration
+	// SelfTest requests the server once listening
+	SelfTest bool
+}
+
+// setDefaults replaces the zero values standing for a default
+func (cfg *Config) setDefaults() {
+	setDefault := func(value *string, def string) {
+		if *value == "" {
+			*value = def
+		}
+	}
+	setDefault(&cfg.ContextRoot, "auto")
+	setDefault(&cfg.ManifestPath, "/_manifest.json")
+	setDefault(&cfg.ManifestFields, "size,hash")
+	setDefault(&cfg.DateHeader, "auto")
+	setDefault(&cfg.CORSAllowOrigin, "*")
+	setDefault(&cfg.CORSAllowMethods, "GET, HEAD, OPTIONS")
+	setDefault(&cfg.GzipTypes, DefaultGzipTypes)
+	setDefault(&cfg.GzipLevelOverrideFrom, "127.0.0.1,::1")
+	setDefault(&cfg.DefaultUser, "gopher")
+	setDefault(&cfg.HealthPath, "/health")
+	setDefault(&cfg.HealthBody, "Ok")
+	setDefault(&cfg.LogFormat, "text")
+	setDefault(&cfg.EncodingPreference, "br,gzip")
+	setDefault(&cfg.LogFields, "method,path,status,bytes,duration,remote_addr")
+	if cfg.GzipLevel == 0 {
+		cfg.GzipLevel = 6
+	}
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = 10 * time.Second
+	}
+	if cfg.PasswordLength == 0 {
+		cfg.PasswordLength = 16
+	}
+	if cfg.ServeStaleSize == 0 {
+		cfg.ServeStaleSize = 64 << 20
+	}
+	if cfg.ServeStaleMaxAge == 0 {
+		cfg.ServeStaleMaxAge = time.Hour
+	}
+	if cfg.CacheCompressedSize == 0 {
+		cfg.CacheCompressedSize = 16 << 20
+	}
+	if cfg.AccessLog == nil {
+		cfg.AccessLog = os.Stderr
+	}
+	// the credentials and the protected paths imply basic auth
+	if cfg.BasicAuthCredentials != "" || cfg.BasicAuthFile != "" || len(cfg.AuthPaths) > 0 {
+		cfg.BasicAuth = true
+	}
+	if cfg.HealthOnly {
+		cfg.EnableHealth = true
+	}
+}
+
+// Server serves the static files of a Config
+type Server struct {
+	cfg        Config
+	mux        *http.ServeMux
+	pathPrefix string
+
+	// chain names the middlewares in the order they are applied, from the
+	// file server outwards
+	chain []string
+
+	defaultPageBytes []byte
+
+	username      string
+	password      string
+	htpasswdUsers map[string]string
+
+	headerConfigs HeaderConfigArray
+
+	encoders              map[string]*sync.Pool
+	encodingPreference    []string
+	gzipTypes             *compressibleTypes
+	compressedResponses   *compressedCache
+	gzipSkipPathRegexp    *regexp.Regexp
+	gzipSkipUARegexp      *regexp.Regexp
+	gzipLevelOverrideNets []*net.IPNet
+
+	trustedProxyNets []*net.IPNet
+
+	logFields   []string
+	geoIPLookup func(ip net.IP) (country, city string)
+
+	// notReady is set once the server should stop receiving traffic
+	notReady int32
+	// inFlight is the number of requests being served, health checks aside
+	inFlight int64
+	// stop receives the termination signals
+	stop chan os.Signal
+}
+
+// use records the name of a middleware wrapping the handler chain
+func (s *Server) use(name string, h http.Handler) http.Handler {
+	s.chain = append(s.chain, name)
+	return h
+}
+
+// logMiddlewareChain logs the middlewares in the order requests go through them
+func (s *Server) logMiddlewareChain() {
+	names := make([]string, 0, len(s.chain))
+	for i := len(s.chain) - 1; i >= 0; i-- {
+		names = append(names, s.chain[i])
+	}
+	log.Println("Middleware chain: " + strings.Join(names, " -> ") + " -> file-server")
+}
+
+// New builds the handler chain of a server: file system, fallback, auth,
+// headers and compression.
+func New(cfg Config) (*Server, error) {
+	cfg.setDefaults()
+	s := &Server{cfg: cfg, mux: http.NewServeMux(), stop: make(chan os.Signal, 1)}
+
+	if err := checkTLSConfig(cfg); err != nil {
+		return nil, err
+	}
+	if err := checkLogFormat(cfg.LogFormat); err != nil {
+		return nil, err
+	}
+	fields, err := parseLogFields(cfg.LogFields)
+	if err != nil {
+		return nil, err
+	}
+	s.logFields = fields
+	if len(cfg.GeoIPDB) > 0 {
+		lookup, err := openGeoIP(cfg.GeoIPDB)
+		if err != nil {
+			return nil, err
+		}
+		s.geoIPLookup = lookup
+	}
+
+	if cfg.HealthOnly {
+		// the health summary is served at the root
+		if err := checkHealthPath(cfg.HealthPath, "/"); err != nil {
+			return nil, err
+		}
+		log.Println("Health-only mode, no files are served")
+		s.mux.HandleFunc(cfg.HealthPath, s.healthHandler)
+		s.mux.HandleFunc("/", s.healthSummaryHandler)
+		s.pathPrefix = "/"
+		return s, nil
+	}
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		return nil, err
+	}
+	cfg = s.cfg
+
+	if cfg.EnableHealth {
+		if err := checkHealthPath(cfg.HealthPath, s.pathPrefix); err != nil {
+			return nil, err
+		}
+		s.mux.HandleFunc(cfg.HealthPath, s.healthHandler)
+	}
+
+	if cfg.PrestopGrace > 0 {
+		if !cfg.BasicAuth {
+			return nil, errors.New("--prestop-grace needs basic auth to protect /admin/prestop")
+		}
+		s.mux.Handle("/admin/prestop", s.authMiddleware(s.prestopHandler(cfg.PrestopGrace)))
+	}
+
+	if cfg.VerboseStartup {
+		s.logMiddlewareChain()
+	}
+
+	s.mux.Handle(s.pathPrefix, handler)
+	return s, nil
+}
+
+// buildHandler builds the chain of middlewares serving the files
+func (s *Server) buildHandler() (http.Handler, error) {
+	cfg := &s.cfg
+
+	root := cfg.FileSystem
+	if root == nil {
+		root = http.Dir(cfg.Path)
+	}
+	// the fallback and error pages are fetched from the origin too
+	if len(cfg.Origin) > 0 {
+		originFiles, err := newOriginFS(cfg.Origin, cfg.OriginCacheTTL, cfg.StaleWhileRevalidate)
+		if err != nil {
+			return nil, err
+		}
+		root = originFiles
+	}
+
+	if cfg.Fallback != "" && !cfg.FailOnMissingFallback && !fileExistsInFS(root, cfg.Fallback) {
+		log.Println("Warning: fallback file " + cfg.Fallback + " not found, fallback disabled")
+		cfg.Fallback = ""
+	}
+
+	fileSystem := root
+	if cfg.MaxFileSize > 0 {
+		fileSystem = maxFileSizeFS{maxSize: cfg.MaxFileSize, fs: fileSystem}
+	}
+	diskFileSystem := fileSystem
+
+	if cfg.DisableDirectoryListing {
+		fileSystem = noListingFS{fs: fileSystem}
+	}
+
+	var stale *staleCache
+	if cfg.ServeStale {
+		stale = newStaleCache(cfg.ServeStaleSize, cfg.ServeStaleMaxAge)
+		fileSystem = staleFS{fs: fileSystem, cache: stale}
+	}
+
+	if cfg.Fallback != "" {
+		if err := s.parseFallbackPage(root, cfg.Fallback); err != nil {
+			return nil, err
+		}
+		fileSystem = fallback{
+			defaultPath:    cfg.Fallback,
+			fs:             fileSystem,
+			defaultContent: s.defaultPageBytes,
+			prefixes:       cfg.FallbackPrefixes,
+		}
+	}
+
+	preference, err := parseEncodingPreference(cfg.EncodingPreference)
+	if err != nil {
+		return nil, err
+	}
+	s.encodingPreference = preference
+
+	if !cfg.DisableCompression || cfg.NoCompressSetVary {
+		if cfg.GzipLevel < gzip.BestSpeed || cfg.GzipLevel > gzip.BestCompression {
+			return nil, errors.New("invalid --gzip-level, must be between 1 and 9")
+		}
+		s.encoders = newEncoderPools(cfg.GzipLevel, cfg.DisableBrotli)
+		if cfg.GzipTypes != "*" {
+			s.gzipTypes = parseGzipTypes(cfg.GzipTypes)
+		}
+		if cfg.CacheCompressed {
+			s.compressedResponses = newCompressedCache(cfg.CacheCompressedSize)
+		}
+		if cfg.AllowGzipLevelOverride {
+			nets, err := parseCIDRs(cfg.GzipLevelOverrideFrom)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --gzip-level-override-from: %v", err)
+			}
+			s.gzipLevelOverrideNets = nets
+		}
+	}
+
+	if len(cfg.GzipSkipPath) > 0 {
+		re, err := regexp.Compile(cfg.GzipSkipPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --gzip-skip-path: %v", err)
+		}
+		s.gzipSkipPathRegexp = re
+	}
+
+	if len(cfg.GzipSkipUA) > 0 {
+		re, err := regexp.Compile(cfg.GzipSkipUA)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --gzip-skip-ua: %v", err)
+		}
+		s.gzipSkipUARegexp = re
+	}
+
+	if cfg.TrustForwardedFor && len(cfg.TrustedProxies) == 0 {
+		return nil, errors.New("--trust-forwarded-for needs --trusted-proxies, the addresses of the proxies setting X-Forwarded-For")
+	}
+	if cfg.TrustXForwardedHost && len(cfg.TrustedProxies) == 0 {
+		return nil, errors.New("--trust-x-forwarded-host needs --trusted-proxies, the addresses of the proxies setting X-Forwarded-Host")
+	}
+	if len(cfg.TrustedProxies) > 0 {
+		nets, err := parseCIDRs(cfg.TrustedProxies)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --trusted-proxies: %v", err)
+		}
+		s.trustedProxyNets = nets
+	}
+
+	// compressAll is set when all the responses go through the compression
+	// middleware, which varies them on Accept-Encoding
+	compressAll := (!cfg.DisableCompression || cfg.NoCompressSetVary) && (!cfg.GzipListingsOnly || cfg.NoCompressSetVary)
+
+	var fileServer http.Handler = http.FileServer(fileSystem)
+	if stale != nil {
+		fileServer = s.use("serve-stale", staleMiddleware(stale, fileServer))
+	}
+	if strings.ContainsAny(cfg.ETagVersion, "\" \t") {
+		return nil, errors.New("invalid --etag-version, it can't contain quotes or spaces")
+	}
+	if cfg.EnableETag || cfg.ETagVersion != "" {
+		fileServer = s.use("etag", s.etagMiddleware(fileSystem, fileServer))
+	}
+	if cfg.GzipListingsOnly {
+		fileServer = s.use("gzip-listings", listingCompressMiddleware(diskFileSystem, s.compressMiddleware(fileServer), fileServer))
+	}
+	if cfg.ServePrecompressed {
+		fileServer = s.use("precompressed", precompressedMiddleware(diskFileSystem, s.encodingPreference, !compressAll, fileServer))
+	}
+	if cfg.SPA {
+		if cfg.Fallback == "" {
+			return nil, errors.New("--spa needs a --fallback page")
+		}
+		fileServer = s.use("spa", s.spaMiddleware(diskFileSystem, fileServer))
+	}
+	extensions := parseCleanURLExtensions(cfg.CleanURLExtensions)
+	if cfg.CleanURLs {
+		extensions = append(extensions, ".html")
+	}
+	if len(extensions) > 0 {
+		fileServer = s.use("clean-url", cleanURLMiddleware(diskFileSystem, extensions, fileServer))
+	}
+	if cfg.CleanURLs {
+		fileServer = s.use("clean-url-redirect", s.cleanURLRedirectMiddleware(diskFileSystem, fileServer))
+	}
+	if len(cfg.ImmutablePattern) > 0 {
+		re, err := regexp.Compile(cfg.ImmutablePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --immutable-pattern: %v", err)
+		}
+		fileServer = s.use("immutable", immutableMiddleware(diskFileSystem, re, fileServer))
+	}
+	if cfg.RespectSaveData {
+		fileServer = s.use("save-data", saveDataMiddleware(diskFileSystem, fileServer))
+	}
+	if cfg.DefaultFavicon {
+		fileServer = s.use("default-favicon", defaultFaviconMiddleware(diskFileSystem, fileServer))
+	}
+	if cfg.EnableManifest {
+		if cfg.FileSystem != nil {
+			return nil, errors.New("--enable-manifest needs the files in a --path directory")
+		}
+		fields, err := parseManifestFields(cfg.ManifestFields)
+		if err != nil {
+			return nil, err
+		}
+		manifest, err := buildManifest(cfg.Path, cfg.ManifestPath, fields)
+		if err != nil {
+			return nil, fmt.Errorf("unable to build the manifest: %v", err)
+		}
+		fileServer = s.use("manifest", manifestMiddleware(cfg.ManifestPath, manifest, fileServer))
+	}
+	if len(cfg.EmptyRootMessage) > 0 {
+		fileServer = s.use("empty-root", emptyRootMiddleware(diskFileSystem, cfg.EmptyRootMessage, fileServer))
+	}
+
+	handler := fileServer
+
+	if cfg.Fallback != "" {
+		handler = s.use("default-page", s.defaultPage(cfg.Fallback, handler))
+	}
+	// outside the default page, so / and the fallback page answer a no-cache
+	// request with a full 200 too
+	handler = s.use("no-cache", noCacheMiddleware(handler))
+
+	if cfg.InjectSRI {
+		prefix := "/"
+		if len(cfg.Context) > 0 {
+			prefix = "/" + cfg.Context + "/"
+		}
+		handler = s.use("sri", sriMiddleware(diskFileSystem, prefix, handler))
+	}
+
+	if err := checkContextRootMode(cfg.ContextRoot, cfg.Fallback); err != nil {
+		return nil, err
+	}
+	if cfg.ContextRoot != "auto" {
+		handler = s.use("context-root", s.contextRootMiddleware(cfg.ContextRoot, diskFileSystem, handler))
+	}
+
+	s.pathPrefix = "/"
+	if len(cfg.Context) > 0 {
+		s.pathPrefix = "/" + cfg.Context + "/"
+		handler = s.use("context-strip", http.StripPrefix(s.pathPrefix, handler))
+	}
+
+	if cfg.BasicAuth {
+		log.Println("Enabling Basic Auth")
+		if len(cfg.BasicAuthFile) != 0 {
+			users, err := loadHtpasswd(cfg.BasicAuthFile)
+			if err != nil {
+				return nil, err
+			}
+			s.htpasswdUsers = users
+		} else if len(cfg.BasicAuthCredentials) != 0 {
+			if err := s.parseAuth(cfg.BasicAuthCredentials); err != nil {
+				return nil, err
+			}
+		} else if err := s.generateRandomAuth(); err != nil {
+			return nil, err
+		}
+		if len(cfg.AuthPaths) > 0 {
+			// the paths are relative to the context, like the fallback prefixes
+			var prefixes []string
+			for _, p := range cfg.AuthPaths {
+				prefixes = append(prefixes, s.pathPrefix+strings.TrimPrefix(p, "/"))
+			}
+			handler = s.use("basic-auth", s.authPathsMiddleware(prefixes, handler))
+		} else {
+			handler = s.use("basic-auth", s.authMiddleware(handler))
+		}
+	}
+
+	if len(cfg.RedirectsConfig) > 0 {
+		rules, err := loadRedirectConfig(cfg.RedirectsConfig)
+		if err != nil {
+			return nil, err
+		}
+		handler = s.use("redirects", s.redirectMiddleware(rules, handler))
+	}
+
+	// preflight requests carry no credentials, answer them before basic auth
+	if cfg.EnableCORS {
+		var origins []string
+		for _, origin := range strings.Split(cfg.CORSAllowOrigin, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				origins = append(origins, origin)
+			}
+		}
+		handler = s.use("cors", corsMiddleware(origins, cfg.CORSAllowMethods, cfg.CORSAllowHeaders, handler))
+	}
+
+	if cfg.HeaderConfigPath != "" {
+		s.headerConfigs = initHeaderConfig(cfg.HeaderConfigPath)
+	}
+	if len(s.headerConfigs.Configs) > 0 || cfg.DefaultCacheControl != "" {
+		handler = s.use("custom-headers", customHeadersMiddleware(s.headerConfigs, cfg.DefaultCacheControl, handler))
+	}
+
+	if cfg.HTMLNoCache {
+		handler = s.use("html-no-cache", htmlNoCacheMiddleware(handler))
+	}
+
+	if len(cfg.QueryHeaders) > 0 {
+		rules, err := parseQueryHeaders(cfg.QueryHeaders)
+		if err != nil {
+			return nil, err
+		}
+		handler = s.use("query-headers", queryHeadersMiddleware(rules, handler))
+	}
+
+	if len(cfg.FixedDate) > 0 {
+		date, err := http.ParseTime(cfg.FixedDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --fixed-date: %v", err)
+		}
+		handler = s.use("date-header", dateHeaderMiddleware([]string{date.UTC().Format(http.TimeFormat)}, handler))
+	} else if cfg.DateHeader == "off" {
+		handler = s.use("date-header", dateHeaderMiddleware(nil, handler))
+	} else if cfg.DateHeader != "auto" {
+		return nil, errors.New("invalid --date-header, must be auto or off")
+	}
+
+	// inside the error pages, so the denied clients get the --forbidden-page
+	if len(cfg.AllowIPs) > 0 || len(cfg.DenyIPs) > 0 {
+		allowed, err := parseCIDRs(strings.Join(cfg.AllowIPs, ","))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --allow-ip: %v", err)
+		}
+		denied, err := parseCIDRs(strings.Join(cfg.DenyIPs, ","))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --deny-ip: %v", err)
+		}
+		handler = s.use("ip-filter", s.ipFilterMiddleware(allowed, denied, handler))
+	}
+
+	// with a fallback, only the paths out of the fallback prefixes are not found
+	if len(cfg.NotFoundPage) > 0 {
+		page, err := loadErrorPage(root, cfg.NotFoundPage)
+		if err != nil {
+			return nil, err
+		}
+		handler = s.use("not-found-page", errorPageMiddleware(http.StatusNotFound, page, handler))
+	}
+
+	if len(cfg.ForbiddenPage) > 0 {
+		page, err := loadErrorPage(root, cfg.ForbiddenPage)
+		if err != nil {
+			return nil, err
+		}
+		handler = s.use("forbidden-page", errorPageMiddleware(http.StatusForbidden, page, handler))
+	}
+
+	// Extra headers.
+	if len(cfg.AppendHeader) > 0 {
+		header, headerValue := parseHeaderFlag(cfg.AppendHeader)
+		if len(header) > 0 && len(headerValue) > 0 {
+			handler = s.use("append-header", appendHeaderMiddleware(header, headerValue, handler))
+		} else {
+			log.Println("appendHeader misconfigured; ignoring.")
+		}
+	}
+
+	if compressAll {
+		handler = s.use("compression", s.compressMiddleware(handler))
+	}
+
+	if len(cfg.CanonicalHost) > 0 {
+		handler = s.use("canonical-host", s.canonicalHostMiddleware(cfg.CanonicalHost, handler))
+	}
+
+	if cfg.RejectDoubleEncoding {
+		handler = s.use("reject-double-encoding", rejectDoubleEncodingMiddleware(handler))
+	}
+
+	// outermost, so the responses of all the other middlewares are logged
+	handler = s.use("request-log", s.handleReq(handler))
+
+	// around the request log too, which does the HTTPS promotion redirects
+	if len(cfg.DefaultHost) > 0 {
+		handler = s.use("default-host", defaultHostMiddleware(cfg.DefaultHost, handler))
+	}
+
+	if cfg.EnableHealth && cfg.HealthMaxInFlight > 0 {
+		handler = s.use("in-flight", s.inFlightMiddleware(handler))
+	}
+
+	return handler, nil
+}
+
+// Handler returns the handler serving the files and the health endpoints
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// ListenAndServe serves the handler on the configured ports until a
+// termination signal is received
+func (s *Server) ListenAndServe() error {
+	return s.listenAndServe(s.mux)
+}
@@ -0,0 +1,138 @@
+package gostatic
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates a static files directory holding files, by path
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func newTestServer(t *testing.T, cfg Config) *Server {
+	t.Helper()
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// serve sends a request through the handler of a server, with the headers
+// given as name and value pairs
+func serve(s *Server, method, target string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, r)
+	return rec
+}
+
+func TestNewServersAreIndependent(t *testing.T) {
+	first := newTestServer(t, Config{
+		Path:                 writeFiles(t, map[string]string{"index.html": "first"}),
+		Fallback:             "/index.html",
+		BasicAuthCredentials: "first:secret",
+	})
+	second := newTestServer(t, Config{
+		Path:     writeFiles(t, map[string]string{"index.html": "second"}),
+		Fallback: "/index.html",
+	})
+
+	if rec := serve(first, "GET", "/"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("first server without credentials: got %v, want 401", rec.Code)
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.SetBasicAuth("first", "secret")
+	rec := httptest.NewRecorder()
+	first.Handler().ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK || rec.Body.String() != "first" {
+		t.Errorf("first server: got %v %q, want 200 \"first\"", rec.Code, rec.Body.String())
+	}
+	if rec := serve(second, "GET", "/"); rec.Code != http.StatusOK || rec.Body.String() != "second" {
+		t.Errorf("second server: got %v %q, want 200 \"second\"", rec.Code, rec.Body.String())
+	}
+}
+
+func TestEncodedPaths(t *testing.T) {
+	content := func(name string) string { return strings.Repeat(name+" is served\n", 100) }
+	files := map[string]string{
+		"index.html":           content("index"),
+		"my docs/read me.txt":  content("read me"),
+		"café/menü.txt":        content("menü"),
+		"日本語.txt":              content("日本語"),
+		"100%.txt":             content("percent"),
+		"a+b&c=d;e,f'g(h).txt": content("special"),
+		"#hash?query.txt":      content("hash"),
+		"headers.json":         `{"configs": [{"path": "/my docs/", "fileExtension": "txt", "headers": [{"key": "X-Rule", "value": "docs"}]}, {"path": "/café/", "fileExtension": "txt", "headers": [{"key": "X-Rule", "value": "café"}]}]}`,
+	}
+	dir := writeFiles(t, files)
+	s := newTestServer(t, Config{
+		Path:             dir,
+		Fallback:         "/index.html",
+		HeaderConfigPath: filepath.Join(dir, "headers.json"),
+	})
+
+	tests := []struct {
+		name     string
+		target   string
+		wantBody string
+		wantRule string
+	}{
+		{"spaces", "/my%20docs/read%20me.txt", content("read me"), "docs"},
+		{"unicode", "/caf%C3%A9/men%C3%BC.txt", content("menü"), "café"},
+		{"lowercase escapes", "/caf%c3%a9/men%c3%bc.txt", content("menü"), "café"},
+		{"cjk", "/%E6%97%A5%E6%9C%AC%E8%AA%9E.txt", content("日本語"), ""},
+		{"percent sign", "/100%25.txt", content("percent"), ""},
+		{"sub-delims", "/a+b&c=d;e,f'g(h).txt", content("special"), ""},
+		{"encoded sub-delims", "/a%2Bb%26c%3Dd%3Be%2Cf%27g%28h%29.txt", content("special"), ""},
+		{"encoded hash and question mark", "/%23hash%3Fquery.txt", content("hash"), ""},
+		{"missing with spaces", "/my%20docs/no%20such%20file", content("index"), ""},
+		{"missing unicode", "/caf%C3%A9/carte", content("index"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(s, "GET", tt.target, "Accept-Encoding", "gzip")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %v, want %v", rec.Code, http.StatusOK)
+			}
+			if got := rec.Header().Get("X-Rule"); got != tt.wantRule {
+				t.Errorf("header rule %q, want %q", got, tt.wantRule)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+				t.Fatalf("Content-Encoding %q, want gzip", got)
+			}
+			zr, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := ioutil.ReadAll(zr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("got %.40q, want %.40q", body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package gostatic
+
+import (
+	"compress/gzip"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// gzipLevelOverride returns the gzip level requested with X-Gzip-Level by
+// a client of the --gzip-level-override-from networks. Invalid levels are
+// ignored.
+func (s *Server) gzipLevelOverride(r *http.Request) (int, bool) {
+	value := r.Header.Get("X-Gzip-Level")
+	if value == "" {
+		return 0, false
+	}
+	ip := net.ParseIP(stripPort(r.RemoteAddr))
+	if ip == nil || !containsIP(s.gzipLevelOverrideNets, ip) {
+		return 0, false
+	}
+	level, err := strconv.Atoi(value)
+	if err != nil || level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return 0, false
+	}
+	return level, true
+}
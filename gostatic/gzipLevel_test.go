@@ -0,0 +1,73 @@
+package gostatic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func gzipped(t *testing.T, data string, level int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = zw.Write([]byte(data))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestGzipLevelOverride(t *testing.T) {
+	content := strings.Repeat("some compressible text, ", 1000)
+	s := newTestServer(t, Config{
+		Path: writeFiles(t, map[string]string{
+			"a.txt":     content,
+			"image.png": content,
+		}),
+		AllowGzipLevelOverride: true,
+		// the address of the httptest requests
+		GzipLevelOverrideFrom: "192.0.2.1",
+	})
+	untrusted := newTestServer(t, Config{
+		Path:                   writeFiles(t, map[string]string{"a.txt": content}),
+		AllowGzipLevelOverride: true,
+	})
+
+	tests := []struct {
+		name       string
+		server     *Server
+		target     string
+		level      string
+		wantHeader string
+		wantLevel  int
+	}{
+		{"valid level", s, "/a.txt", "1", "1", 1},
+		{"best compression", s, "/a.txt", "9", "9", 9},
+		{"out of range level", s, "/a.txt", "12", "", 6},
+		{"negative level", s, "/a.txt", "-3", "", 6},
+		{"not a number", s, "/a.txt", "fast", "", 6},
+		{"untrusted client", untrusted, "/a.txt", "1", "", 6},
+		{"uncompressed response", s, "/image.png", "1", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(tt.server, "GET", tt.target, "Accept-Encoding", "gzip", "X-Gzip-Level", tt.level)
+			if got := rec.Header().Get("X-Gzip-Level"); got != tt.wantHeader {
+				t.Errorf("X-Gzip-Level: got %q, want %q", got, tt.wantHeader)
+			}
+			if tt.wantLevel == 0 {
+				if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != content {
+					t.Errorf("got a compressed response, want the file as is")
+				}
+				return
+			}
+			if rec.Body.String() != gzipped(t, content, tt.wantLevel) {
+				t.Errorf("body isn't compressed at level %v", tt.wantLevel)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package gostatic
+
+import (
+	"mime"
+	"path"
+	"strings"
+)
+
+// DefaultGzipTypes are the extensions of the text assets compressed by default
+const DefaultGzipTypes = ".html,.css,.js,.json,.svg,.xml,.txt,.map"
+
+// compressibleTypes are the files worth compressing, by extension of the
+// request path or by media type of the response, for the paths without
+// extension like directories and the fallback page
+type compressibleTypes struct {
+	extensions map[string]bool
+	mediaTypes map[string]bool
+}
+
+// parseGzipTypes parses a comma separated list of extensions, with or
+// without their leading dot
+func parseGzipTypes(list string) *compressibleTypes {
+	types := &compressibleTypes{extensions: make(map[string]bool), mediaTypes: make(map[string]bool)}
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		types.extensions[ext] = true
+		if mediaType, _, err := mime.ParseMediaType(mime.TypeByExtension(ext)); err == nil {
+			types.mediaTypes[mediaType] = true
+		}
+	}
+	return types
+}
+
+// match tells whether the response of a path, of the given media type, is
+// worth compressing. A nil set matches all of them.
+func (t *compressibleTypes) match(urlPath, mediaType string) bool {
+	if t == nil {
+		return true
+	}
+	return t.extensions[strings.ToLower(path.Ext(urlPath))] || t.mediaTypes[mediaType]
+}
@@ -0,0 +1,118 @@
+package gostatic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+var startTime = time.Now()
+
+// checkHealthPath makes sure the health check doesn't take over the root
+// or the path prefix the files are served at
+func checkHealthPath(healthPath, pathPrefix string) error {
+	if !strings.HasPrefix(healthPath, "/") {
+		return errors.New("--health-path must start with /")
+	}
+	if healthPath == "/" || healthPath == pathPrefix {
+		return fmt.Errorf("--health-path %v conflicts with the root or the --context path", healthPath)
+	}
+	return nil
+}
+
+// inFlightMiddleware counts the requests being served
+func (s *Server) inFlightMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&s.inFlight, 1)
+		defer atomic.AddInt64(&s.inFlight, -1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// healthStatus returns the status of the health check, and its reason when
+// failing
+func (s *Server) healthStatus() (int, string) {
+	if atomic.LoadInt32(&s.notReady) != 0 {
+		return http.StatusServiceUnavailable, "Unavailable"
+	}
+	// an overloaded instance asks the load balancer for less traffic
+	if s.cfg.HealthMaxInFlight > 0 && atomic.LoadInt64(&s.inFlight) > int64(s.cfg.HealthMaxInFlight) {
+		return http.StatusServiceUnavailable, "Overloaded"
+	}
+	return http.StatusOK, "Ok"
+}
+
+func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
+	if status, reason := s.healthStatus(); status != http.StatusOK {
+		http.Error(w, reason, status)
+		return
+	}
+	if json.Valid([]byte(s.cfg.HealthBody)) {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	_, _ = fmt.Fprint(w, s.cfg.HealthBody)
+}
+
+// setReady makes the health check succeed or fail
+func (s *Server) setReady(ready bool) {
+	if ready {
+		atomic.StoreInt32(&s.notReady, 0)
+	} else {
+		atomic.StoreInt32(&s.notReady, 1)
+	}
+}
+
+// watchReadinessSignals makes the health check fail on SIGUSR1, and
+// succeed again on SIGUSR2, e.g. to take the server out of a load balancer
+// while keeping it running. It stops watching once done is closed.
+func (s *Server) watchReadinessSignals(done <-chan struct{}) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case sig := <-signals:
+				ready := sig == syscall.SIGUSR2
+				log.Printf("Received %v, ready: %v\n", sig, ready)
+				s.setReady(ready)
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// prestopHandler is meant for Kubernetes preStop hooks: it makes the health
+// check fail, then waits for grace so load balancers stop routing to the
+// server before it receives SIGTERM
+func (s *Server) prestopHandler(grace time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.setReady(false)
+		log.Printf("Pre-stop requested, waiting %v\n", grace)
+		time.Sleep(grace)
+		_, _ = fmt.Fprintf(w, "Ok")
+	})
+}
+
+// healthSummaryHandler serves the root in health-only mode, with the status
+// of the health check. Any other path is not found.
+func (s *Server) healthSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	status, reason := s.healthStatus()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "goStatic is running in health-only mode\nstatus: %v\nin-flight: %v\nuptime: %v\n",
+		reason, atomic.LoadInt64(&s.inFlight), time.Since(startTime).Round(time.Second))
+}
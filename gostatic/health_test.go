@@ -0,0 +1,183 @@
+package gostatic
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHealthMaxInFlight(t *testing.T) {
+	s := newTestServer(t, Config{
+		Path:              writeFiles(t, nil),
+		EnableHealth:      true,
+		HealthMaxInFlight: 3,
+	})
+
+	tests := []struct {
+		inFlight int64
+		want     int
+	}{
+		{0, http.StatusOK},
+		{3, http.StatusOK},
+		{4, http.StatusServiceUnavailable},
+		{100, http.StatusServiceUnavailable},
+		{2, http.StatusOK},
+	}
+	for _, tt := range tests {
+		atomic.StoreInt64(&s.inFlight, tt.inFlight)
+		if rec := serve(s, "GET", "/health"); rec.Code != tt.want {
+			t.Errorf("%v requests in flight: got %v, want %v", tt.inFlight, rec.Code, tt.want)
+		}
+	}
+	atomic.StoreInt64(&s.inFlight, 0)
+}
+
+func TestHealthMaxInFlightRequests(t *testing.T) {
+	s := newTestServer(t, Config{
+		Path:              writeFiles(t, nil),
+		EnableHealth:      true,
+		HealthMaxInFlight: 3,
+	})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	slow := s.inFlightMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slow.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+		}()
+		<-started
+
+		want := http.StatusOK
+		if i > 3 {
+			want = http.StatusServiceUnavailable
+		}
+		if rec := serve(s, "GET", "/health"); rec.Code != want {
+			t.Errorf("%v requests in flight: got %v, want %v", i, rec.Code, want)
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	if rec := serve(s, "GET", "/health"); rec.Code != http.StatusOK {
+		t.Errorf("once the requests completed: got %v, want 200", rec.Code)
+	}
+}
+
+func TestHealthPathConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"root", Config{EnableHealth: true, HealthPath: "/"}, true},
+		{"context", Config{EnableHealth: true, Context: "app", HealthPath: "/app/"}, true},
+		{"root with a context", Config{EnableHealth: true, Context: "app", HealthPath: "/"}, true},
+		{"relative", Config{EnableHealth: true, HealthPath: "health"}, true},
+		{"health-only root", Config{HealthOnly: true, HealthPath: "/"}, true},
+		{"under the context", Config{EnableHealth: true, Context: "app", HealthPath: "/app/health"}, false},
+		{"context without slash", Config{EnableHealth: true, Context: "app", HealthPath: "/app"}, false},
+		{"health-only", Config{HealthOnly: true, HealthPath: "/healthz"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Path = writeFiles(t, nil)
+			_, err := New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want one: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHealthSummary(t *testing.T) {
+	s := newTestServer(t, Config{HealthOnly: true, HealthMaxInFlight: 3})
+
+	tests := []struct {
+		name       string
+		ready      bool
+		inFlight   int64
+		wantStatus int
+		wantLine   string
+	}{
+		{"ready", true, 0, http.StatusOK, "status: Ok"},
+		{"not ready", false, 0, http.StatusServiceUnavailable, "status: Unavailable"},
+		{"overloaded", true, 4, http.StatusServiceUnavailable, "status: Overloaded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.setReady(tt.ready)
+			atomic.StoreInt64(&s.inFlight, tt.inFlight)
+			defer atomic.StoreInt64(&s.inFlight, 0)
+
+			for _, target := range []string{"/", "/health"} {
+				if rec := serve(s, "GET", target); rec.Code != tt.wantStatus {
+					t.Errorf("%v: got %v, want %v", target, rec.Code, tt.wantStatus)
+				}
+			}
+			rec := serve(s, "GET", "/")
+			if !strings.Contains(rec.Body.String(), tt.wantLine+"\n") {
+				t.Errorf("got %q, want a %q line", rec.Body.String(), tt.wantLine)
+			}
+			if want := fmt.Sprintf("in-flight: %v\n", tt.inFlight); !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("got %q, want a %q line", rec.Body.String(), want)
+			}
+		})
+	}
+	if rec := serve(s, "GET", "/a.txt"); rec.Code != http.StatusNotFound {
+		t.Errorf("other path: got %v, want 404", rec.Code)
+	}
+}
+
+func TestReadinessSignals(t *testing.T) {
+	// keeps the signals from killing the test process once the server
+	// stops watching them
+	guard := make(chan os.Signal, 4)
+	signal.Notify(guard, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(guard)
+
+	s := newTestServer(t, Config{Path: writeFiles(t, nil), EnableHealth: true})
+	done := make(chan struct{})
+	s.watchReadinessSignals(done)
+
+	// waitHealth waits for the health check to answer want
+	waitHealth := func(want int) bool {
+		for i := 0; i < 100; i++ {
+			if serve(s, "GET", "/health").Code == want {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+	_ = syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	if !waitHealth(http.StatusServiceUnavailable) {
+		t.Fatal("health check still succeeding after SIGUSR1")
+	}
+	_ = syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	if !waitHealth(http.StatusOK) {
+		t.Fatal("health check still failing after SIGUSR2")
+	}
+
+	close(done)
+	time.Sleep(50 * time.Millisecond)
+	_ = syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	time.Sleep(50 * time.Millisecond)
+	if rec := serve(s, "GET", "/health"); rec.Code != http.StatusOK {
+		t.Errorf("got %v once the server stopped watching the signals, want 200", rec.Code)
+	}
+}
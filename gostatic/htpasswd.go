@@ -0,0 +1,57 @@
+package gostatic
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// bcryptCompare checks a password against a bcrypt hash. It is only set
+// when built with the bcrypt tag, keeping the dependency optional.
+var bcryptCompare func(hash, password []byte) error
+
+// loadHtpasswd parses an Apache htpasswd file of user:hash lines, mapping
+// the users to their bcrypt hash. Only bcrypt hashes, as generated by
+// htpasswd -B, are supported.
+func loadHtpasswd(path string) (map[string]string, error) {
+	if bcryptCompare == nil {
+		return nil, errors.New("bcrypt support is not built in, build with -tags bcrypt")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pieces := strings.SplitN(line, ":", 2)
+		if len(pieces) != 2 || pieces[0] == "" {
+			return nil, fmt.Errorf("%v:%v: must be like user:hash", path, n)
+		}
+		if !strings.HasPrefix(pieces[1], "$2") {
+			return nil, fmt.Errorf("%v:%v: the hash of %v isn't bcrypt, generate it with htpasswd -B", path, n, pieces[0])
+		}
+		users[pieces[0]] = pieces[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("%v: no users", path)
+	}
+	return users, nil
+}
+
+// checkHtpasswd reports whether the password matches the hash of the user
+func checkHtpasswd(users map[string]string, user, password string) bool {
+	hash, ok := users[user]
+	return ok && bcryptCompare([]byte(hash), []byte(password)) == nil
+}
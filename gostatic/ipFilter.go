@@ -0,0 +1,74 @@
+package gostatic
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// parseCIDRs parses a comma separated list of CIDRs or single IPs
+func parseCIDRs(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %v", s, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// fromTrustedProxy reports whether the request comes from one of the
+// --trusted-proxies. When there are none, no peer is trusted.
+func (s *Server) fromTrustedProxy(r *http.Request) bool {
+	ip := net.ParseIP(stripPort(r.RemoteAddr))
+	return ip != nil && containsIP(s.trustedProxyNets, ip)
+}
+
+// clientIP returns the IP of the client. With --trust-forwarded-for, it is
+// the last X-Forwarded-For entry, the one added by the proxy in front of
+// goStatic, as the previous ones can be forged by the client.
+func (s *Server) clientIP(r *http.Request) net.IP {
+	if s.cfg.TrustForwardedFor && s.fromTrustedProxy(r) {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			entries := strings.Split(forwarded, ",")
+			return net.ParseIP(strings.TrimSpace(entries[len(entries)-1]))
+		}
+	}
+	return net.ParseIP(stripPort(r.RemoteAddr))
+}
+
+// ipFilterMiddleware answers 403 to the clients in a denied network, or
+// outside the allowed ones when there are any. Denied networks win.
+func (s *Server) ipFilterMiddleware(allowed, denied []*net.IPNet, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := s.clientIP(r)
+		if ip == nil || containsIP(denied, ip) || (len(allowed) > 0 && !containsIP(allowed, ip)) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,69 @@
+package gostatic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// serveFrom sends a GET request from the remoteAddr peer, with an optional
+// X-Forwarded-For header
+func serveFrom(s *Server, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/a.txt", nil)
+	r.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, r)
+	return rec
+}
+
+func TestIPFilter(t *testing.T) {
+	path := writeFiles(t, map[string]string{"a.txt": "a", "403.html": "go away"})
+	tests := []struct {
+		name         string
+		cfg          Config
+		remoteAddr   string
+		forwardedFor string
+		wantStatus   int
+	}{
+		{"no allow list", Config{DenyIPs: []string{"192.168.1.0/24"}}, "10.1.2.3:1234", "", http.StatusOK},
+		{"denied", Config{DenyIPs: []string{"192.168.1.0/24"}}, "192.168.1.7:1234", "", http.StatusForbidden},
+		{"allowed", Config{AllowIPs: []string{"10.0.0.0/8"}}, "10.1.2.3:1234", "", http.StatusOK},
+		{"not allowed", Config{AllowIPs: []string{"10.0.0.0/8"}}, "172.16.0.1:1234", "", http.StatusForbidden},
+		{"deny wins", Config{AllowIPs: []string{"10.0.0.0/8"}, DenyIPs: []string{"10.0.0.5"}}, "10.0.0.5:1234", "", http.StatusForbidden},
+		{"ipv6", Config{AllowIPs: []string{"2001:db8::/32"}}, "[2001:db8::1]:1234", "", http.StatusOK},
+		{"forwarded for ignored", Config{AllowIPs: []string{"10.0.0.0/8"}}, "172.16.0.1:1234", "10.1.2.3", http.StatusForbidden},
+		{"forwarded for from a trusted proxy", Config{AllowIPs: []string{"10.0.0.0/8"}, TrustForwardedFor: true, TrustedProxies: "172.16.0.1"}, "172.16.0.1:1234", "10.1.2.3", http.StatusOK},
+		{"forwarded for from another peer", Config{AllowIPs: []string{"10.0.0.0/8"}, TrustForwardedFor: true, TrustedProxies: "172.16.0.1"}, "172.16.0.2:1234", "10.1.2.3", http.StatusForbidden},
+		{"last forwarded for entry", Config{DenyIPs: []string{"10.6.6.6"}, TrustForwardedFor: true, TrustedProxies: "172.16.0.1"}, "172.16.0.1:1234", "10.1.2.3, 10.6.6.6", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Path = path
+			s := newTestServer(t, tt.cfg)
+			if rec := serveFrom(s, tt.remoteAddr, tt.forwardedFor); rec.Code != tt.wantStatus {
+				t.Errorf("got %v, want %v", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestIPFilterForbiddenPage(t *testing.T) {
+	s := newTestServer(t, Config{
+		Path:          writeFiles(t, map[string]string{"a.txt": "a", "403.html": "go away"}),
+		DenyIPs:       []string{"192.168.1.0/24"},
+		ForbiddenPage: "/403.html",
+	})
+	rec := serveFrom(s, "192.168.1.7:1234", "")
+	if rec.Code != http.StatusForbidden || rec.Body.String() != "go away" {
+		t.Errorf("got %v %q, want 403 \"go away\"", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTrustForwardedForNeedsTrustedProxies(t *testing.T) {
+	if _, err := New(Config{Path: t.TempDir(), TrustForwardedFor: true}); err == nil {
+		t.Error("New succeeded without --trusted-proxies")
+	}
+}
@@ -0,0 +1,69 @@
+package gostatic
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// keepAliveOptions returns the SO_KEEPALIVE and TCP_KEEPIDLE options of a
+// connection
+func keepAliveOptions(t *testing.T, conn net.Conn) (keepAlive, idle int) {
+	t.Helper()
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errKeepAlive, errIdle error
+	err = raw.Control(func(fd uintptr) {
+		keepAlive, errKeepAlive = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+		idle, errIdle = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE)
+	})
+	for _, err := range []error{err, errKeepAlive, errIdle} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return keepAlive, idle
+}
+
+func TestTCPKeepAlive(t *testing.T) {
+	tests := []struct {
+		period        time.Duration
+		wantKeepAlive int
+		wantIdle      int
+	}{
+		{42 * time.Second, 1, 42},
+		{5 * time.Minute, 1, 300},
+		{0, 0, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.period.String(), func(t *testing.T) {
+			s := newTestServer(t, Config{Path: writeFiles(t, nil), TCPKeepAlive: tt.period})
+			ln, err := s.listen(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			client, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+			conn, err := ln.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			keepAlive, idle := keepAliveOptions(t, conn)
+			if keepAlive != tt.wantKeepAlive {
+				t.Errorf("SO_KEEPALIVE: got %v, want %v", keepAlive, tt.wantKeepAlive)
+			}
+			if tt.wantIdle >= 0 && idle != tt.wantIdle {
+				t.Errorf("TCP_KEEPIDLE: got %vs, want %vs", idle, tt.wantIdle)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package gostatic
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func checkLogFormat(format string) error {
+	switch format {
+	case "text", "logfmt", "common", "combined", "json":
+		return nil
+	}
+	return fmt.Errorf("unknown log format %q, must be text, logfmt, common, combined or json", format)
+}
+
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// availableLogFields are the fields which can be emitted in structured logs
+var availableLogFields = []string{"method", "path", "query", "host", "proto", "status", "bytes", "duration", "remote_addr", "user_agent", "referer", "request_id", "country", "city"}
+
+func (s *Server) logFieldValue(field string, r *http.Request, rec *statusRecorder, duration time.Duration) string {
+	switch field {
+	case "method":
+		return r.Method
+	case "path":
+		return r.URL.Path
+	case "query":
+		return r.URL.RawQuery
+	case "host":
+		return r.Host
+	case "proto":
+		return r.Proto
+	case "status":
+		return strconv.Itoa(rec.statusCode())
+	case "bytes":
+		return strconv.Itoa(rec.bytes)
+	case "duration":
+		return duration.String()
+	case "remote_addr":
+		return r.RemoteAddr
+	case "user_agent":
+		return r.UserAgent()
+	case "referer":
+		return r.Referer()
+	case "request_id":
+		return r.Header.Get("X-Request-Id")
+	case "country":
+		country, _ := s.clientLocation(r)
+		return country
+	case "city":
+		_, city := s.clientLocation(r)
+		return city
+	}
+	return ""
+}
+
+// parseLogFields checks the comma separated list of the fields emitted in
+// structured logs, in order
+func parseLogFields(fields string) ([]string, error) {
+	var selected []string
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !isLogField(field) {
+			return nil, fmt.Errorf("unknown log field %q", field)
+		}
+		selected = append(selected, field)
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no log field selected")
+	}
+	return selected, nil
+}
+
+func isLogField(field string) bool {
+	for _, f := range availableLogFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// logfmtLine formats a served request as key=value pairs
+func (s *Server) logfmtLine(r *http.Request, rec *statusRecorder, duration time.Duration) string {
+	pairs := make([]string, 0, len(s.logFields))
+	for _, field := range s.logFields {
+		pairs = append(pairs, field+"="+logfmtValue(s.logFieldValue(field, r, rec, duration)))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// commonLogLine formats a served request in the NCSA Common Log Format, or
+// the Combined Log Format which adds the referer and the user agent
+func commonLogLine(r *http.Request, rec *statusRecorder, start time.Time, combined bool) string {
+	user, _, _ := r.BasicAuth()
+	bytes := "-"
+	if rec.bytes > 0 {
+		bytes = strconv.Itoa(rec.bytes)
+	}
+	line := fmt.Sprintf("%s - %s [%s] %q %d %s",
+		stripPort(r.RemoteAddr), orDash(user), start.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method+" "+r.RequestURI+" "+r.Proto, rec.statusCode(), bytes)
+	if combined {
+		line += fmt.Sprintf(" %q %q", orDash(r.Referer()), orDash(r.UserAgent()))
+	}
+	return line
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// jsonLogLine formats a served request as a JSON object of the log fields
+func (s *Server) jsonLogLine(r *http.Request, rec *statusRecorder, duration time.Duration) string {
+	pairs := make([]string, 0, len(s.logFields))
+	for _, field := range s.logFields {
+		var value interface{}
+		switch field {
+		case "status":
+			value = rec.statusCode()
+		case "bytes":
+			value = rec.bytes
+		default:
+			value = s.logFieldValue(field, r, rec, duration)
+		}
+		encoded, _ := json.Marshal(value)
+		pairs = append(pairs, strconv.Quote(field)+":"+string(encoded))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// accessLogLine formats a served request in the --log-format
+func (s *Server) accessLogLine(r *http.Request, rec *statusRecorder, start time.Time) string {
+	switch s.cfg.LogFormat {
+	case "common":
+		return commonLogLine(r, rec, start, false)
+	case "combined":
+		return commonLogLine(r, rec, start, true)
+	case "json":
+		return s.jsonLogLine(r, rec, time.Since(start))
+	}
+	return s.logfmtLine(r, rec, time.Since(start))
+}
+
+// redactedHeaders are never written to the logs, they carry credentials
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+	"X-Auth-Token":        true,
+}
+
+// headersDump formats headers on one line, sorted by name, with the
+// values of the credential headers redacted
+func headersDump(h http.Header) string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(h[name], ", ")
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			value = "[redacted]"
+		}
+		pairs = append(pairs, name+": "+strconv.Quote(value))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// handleReq redirects to HTTPS when asked to, and logs the requests
+func (s *Server) handleReq(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.HTTPSPromote && r.Header.Get("X-Forwarded-Proto") == "http" {
+			s.redirect(w, r, "https://"+s.requestHost(r)+r.RequestURI, http.StatusMovedPermanently)
+			if s.cfg.EnableLogging {
+				log.Println(301, r.Method, r.URL.Path)
+			}
+			return
+		}
+
+		if s.cfg.LogHeaders {
+			log.Println("Request headers", r.Method, r.URL.Path, headersDump(r.Header))
+			defer func() {
+				log.Println("Response headers", r.Method, r.URL.Path, headersDump(w.Header()))
+			}()
+		}
+
+		if !s.cfg.EnableLogging {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w}
+		start := time.Now()
+		h.ServeHTTP(rec, r)
+		switch s.cfg.LogFormat {
+		case "text":
+			log.Println(rec.statusCode(), r.Method, r.URL.Path)
+		case "logfmt":
+			log.Println(s.accessLogLine(r, rec, start))
+		default:
+			// these formats have their own timestamp, or none for JSON
+			fmt.Fprintln(s.cfg.AccessLog, s.accessLogLine(r, rec, start))
+		}
+	})
+}
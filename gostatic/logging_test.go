@@ -0,0 +1,39 @@
+package gostatic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRequestLogCoversAllResponses(t *testing.T) {
+	var logs bytes.Buffer
+	s := newTestServer(t, Config{
+		Path:                 writeFiles(t, map[string]string{"index.html": "home", "a.txt": "a"}),
+		Fallback:             "/index.html",
+		BasicAuthCredentials: "user:pass",
+		EnableCORS:           true,
+		EnableLogging:        true,
+		LogFormat:            "json",
+		LogFields:            "method,path,status",
+		AccessLog:            &logs,
+	})
+
+	tests := []struct {
+		method  string
+		path    string
+		headers []string
+		want    string
+	}{
+		{"GET", "/", nil, `{"method":"GET","path":"/","status":401}`},
+		{"GET", "/", []string{"Authorization", "Basic dXNlcjpwYXNz"}, `{"method":"GET","path":"/","status":200}`},
+		{"OPTIONS", "/a.txt", []string{"Origin", "https://example.com", "Access-Control-Request-Method", "GET"}, `{"method":"OPTIONS","path":"/a.txt","status":204}`},
+	}
+	for _, tt := range tests {
+		logs.Reset()
+		serve(s, tt.method, tt.path, tt.headers...)
+		if got := strings.TrimSpace(logs.String()); got != tt.want {
+			t.Errorf("%v %v: logged %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
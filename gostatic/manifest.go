@@ -0,0 +1,103 @@
+package gostatic
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// availableManifestFields are the fields which can be listed for each file
+var availableManifestFields = []string{"size", "hash", "modified"}
+
+func parseManifestFields(fields string) ([]string, error) {
+	var selected []string
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		known := false
+		for _, f := range availableManifestFields {
+			known = known || f == field
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown manifest field %q, must be among size, hash and modified", field)
+		}
+		selected = append(selected, field)
+	}
+	return selected, nil
+}
+
+func fileHash(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256-" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// buildManifest lists the files under root with the selected fields, as JSON
+func buildManifest(root string, manifestPath string, fields []string) ([]byte, error) {
+	files := []map[string]interface{}{}
+	err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		urlPath := "/" + filepath.ToSlash(rel)
+		if urlPath == manifestPath {
+			return nil
+		}
+
+		entry := map[string]interface{}{"path": urlPath}
+		for _, field := range fields {
+			switch field {
+			case "size":
+				entry["size"] = info.Size()
+			case "modified":
+				entry["modified"] = info.ModTime().UTC().Format(time.RFC3339)
+			case "hash":
+				hash, err := fileHash(name)
+				if err != nil {
+					return err
+				}
+				entry["hash"] = hash
+			}
+		}
+		files = append(files, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{"files": files})
+}
+
+// manifestMiddleware serves the manifest at manifestPath
+func manifestMiddleware(manifestPath string, manifest []byte, next http.Handler) http.Handler {
+	generated := time.Now()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "/"+strings.TrimPrefix(r.URL.Path, "/") != manifestPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		http.ServeContent(w, r, manifestPath, generated, bytes.NewReader(manifest))
+	})
+}
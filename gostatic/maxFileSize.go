@@ -0,0 +1,30 @@
+package gostatic
+
+import (
+	"net/http"
+	"os"
+)
+
+// maxFileSizeFS refuses to open files larger than maxSize, which makes
+// http.FileServer answer 403 for them
+type maxFileSizeFS struct {
+	maxSize int64
+	fs      http.FileSystem
+}
+
+func (m maxFileSizeFS) Open(name string) (http.File, error) {
+	f, err := m.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !info.IsDir() && info.Size() > m.maxSize {
+		f.Close()
+		return nil, os.ErrPermission
+	}
+	return f, nil
+}
@@ -0,0 +1,37 @@
+package gostatic
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMaxFileSize(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"under.txt": strings.Repeat("u", 99),
+		"limit.txt": strings.Repeat("l", 100),
+		"over.txt":  strings.Repeat("o", 101),
+		"dir/a.txt": "a",
+	})
+
+	tests := []struct {
+		name        string
+		maxFileSize int64
+		target      string
+		want        int
+	}{
+		{"just under", 100, "/under.txt", http.StatusOK},
+		{"at the limit", 100, "/limit.txt", http.StatusOK},
+		{"just over", 100, "/over.txt", http.StatusForbidden},
+		{"directory", 1, "/dir/", http.StatusOK},
+		{"unlimited", 0, "/over.txt", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, Config{Path: dir, MaxFileSize: tt.maxFileSize})
+			if rec := serve(s, "GET", tt.target); rec.Code != tt.want {
+				t.Errorf("got %v, want %v", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package gostatic
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// originCacheSize is the maximum size in bytes of the files kept from the origin
+const originCacheSize = 64 << 20
+
+// originFile is a file fetched from the origin
+type originFile struct {
+	content []byte
+	modTime time.Time
+	etag    string
+	expires time.Time
+}
+
+// originFileInfo describes a file fetched from the origin, or the root
+// directory
+type originFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (fi originFileInfo) Name() string {
+	return fi.name
+}
+
+func (fi originFileInfo) Size() int64 {
+	return fi.size
+}
+
+func (fi originFileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (fi originFileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi originFileInfo) IsDir() bool {
+	return fi.dir
+}
+
+func (fi originFileInfo) Sys() interface{} {
+	return nil
+}
+
+// originFS fetches the files from an upstream HTTP server, and keeps them
+// in memory for the time allowed by the upstream cache headers, or ttl
+// when set
+type originFS struct {
+	base   string
+	ttl    time.Duration
+	client *http.Client
+	// staleWhileRevalidate is how long an expired file is still served
+	// while it is fetched again in the background
+	staleWhileRevalidate time.Duration
+
+	entries *boundedCache
+
+	// the mutex guards refreshing, the files being revalidated
+	sync.Mutex
+	refreshing map[string]bool
+}
+
+func newOriginFS(base string, ttl, staleWhileRevalidate time.Duration) (*originFS, error) {
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid origin %q, must be like https://cdn.example.com", base)
+	}
+	return &originFS{
+		base:                 strings.TrimSuffix(base, "/"),
+		ttl:                  ttl,
+		client:               &http.Client{Timeout: 30 * time.Second},
+		staleWhileRevalidate: staleWhileRevalidate,
+		entries:              newBoundedCache(originCacheSize),
+		refreshing:           make(map[string]bool),
+	}, nil
+}
+
+func (o *originFS) Open(name string) (http.File, error) {
+	name = path.Clean("/" + name)
+	// the origin can't be listed, the root is a directory only holding its index.html
+	if name == "/" {
+		return memoryFile{
+			Reader: bytes.NewReader(nil),
+			info:   originFileInfo{name: "/", modTime: startTime, dir: true},
+		}, nil
+	}
+
+	var entry originFile
+	cached, ok := o.entries.get(name)
+	if ok {
+		entry = cached.(originFile)
+	}
+	now := time.Now()
+	switch {
+	case ok && now.Before(entry.expires):
+	case ok && now.Before(entry.expires.Add(o.staleWhileRevalidate)):
+		o.revalidate(name)
+	default:
+		var err error
+		if entry, err = o.fetch(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return memoryFile{
+		Reader: bytes.NewReader(entry.content),
+		info:   originFileInfo{name: path.Base(name), size: int64(len(entry.content)), modTime: entry.modTime},
+		etag:   entry.etag,
+	}, nil
+}
+
+// revalidate fetches a file again in the background. Concurrent requests
+// for the same file share a single fetch.
+func (o *originFS) revalidate(name string) {
+	o.Lock()
+	defer o.Unlock()
+	if o.refreshing[name] {
+		return
+	}
+	o.refreshing[name] = true
+	go func() {
+		if _, err := o.fetch(name); err != nil {
+			log.Println("Unable to revalidate " + name + " from the origin: " + err.Error())
+		}
+		o.Lock()
+		delete(o.refreshing, name)
+		o.Unlock()
+	}()
+}
+
+// fetch gets a file from the origin, and caches it when allowed
+func (o *originFS) fetch(name string) (originFile, error) {
+	resp, err := o.client.Get(o.base + (&url.URL{Path: name}).EscapedPath())
+	if err != nil {
+		return originFile{}, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return originFile{}, os.ErrNotExist
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusUnauthorized:
+		return originFile{}, os.ErrPermission
+	case resp.StatusCode != http.StatusOK:
+		return originFile{}, fmt.Errorf("origin answered %v for %v", resp.Status, name)
+	}
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return originFile{}, err
+	}
+	entry := originFile{content: content, etag: resp.Header.Get("ETag")}
+	if entry.modTime, err = http.ParseTime(resp.Header.Get("Last-Modified")); err != nil {
+		entry.modTime = time.Now()
+	}
+
+	ttl := o.ttl
+	if ttl <= 0 {
+		ttl = originTTL(resp.Header)
+	}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+		o.entries.add(name, entry, len(entry.content))
+	}
+	return entry, nil
+}
+
+// originTTL returns how long a response may be cached according to its
+// Cache-Control or Expires headers, 0 when it mustn't or they say nothing
+func originTTL(h http.Header) time.Duration {
+	var maxAge, sMaxAge string
+	for _, directive := range strings.Split(h.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store" || directive == "no-cache" || directive == "private":
+			return 0
+		case strings.HasPrefix(directive, "max-age="):
+			maxAge = directive[len("max-age="):]
+		case strings.HasPrefix(directive, "s-maxage="):
+			sMaxAge = directive[len("s-maxage="):]
+		}
+	}
+	// s-maxage is meant for shared caches, like this one
+	if sMaxAge != "" {
+		maxAge = sMaxAge
+	}
+	if maxAge != "" {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil || seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if expires, err := http.ParseTime(h.Get("Expires")); err == nil {
+		if ttl := time.Until(expires); ttl > 0 {
+			return ttl
+		}
+	}
+	return 0
+}
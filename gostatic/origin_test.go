@@ -0,0 +1,172 @@
+package gostatic
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestOrigin serves files with the given Cache-Control, counting the
+// requests by path
+func newTestOrigin(t *testing.T, cacheControl string, files map[string]string) (*httptest.Server, map[string]*int64) {
+	t.Helper()
+	counts := make(map[string]*int64)
+	for name := range files {
+		counts[name] = new(int64)
+	}
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt64(counts[r.URL.Path], 1)
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	t.Cleanup(origin.Close)
+	return origin, counts
+}
+
+func TestOriginCaching(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		ttl          time.Duration
+		wantFetches  int64
+	}{
+		{"max-age", "public, max-age=60", 0, 1},
+		{"s-maxage wins", "max-age=0, s-maxage=60", 0, 1},
+		{"no-store", "no-store", 0, 3},
+		{"no cache headers", "", 0, 3},
+		{"ttl override", "no-cache", time.Minute, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origin, counts := newTestOrigin(t, tt.cacheControl, map[string]string{"/app.js": "console.log(1)"})
+			s := newTestServer(t, Config{Origin: origin.URL, OriginCacheTTL: tt.ttl, DisableCompression: true})
+			for i := 0; i < 3; i++ {
+				rec := serve(s, "GET", "/app.js")
+				if rec.Code != http.StatusOK || rec.Body.String() != "console.log(1)" {
+					t.Fatalf("got %v %q, want 200 \"console.log(1)\"", rec.Code, rec.Body.String())
+				}
+			}
+			if got := atomic.LoadInt64(counts["/app.js"]); got != tt.wantFetches {
+				t.Errorf("origin fetched %v times, want %v", got, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func TestOriginMissingFile(t *testing.T) {
+	origin, _ := newTestOrigin(t, "max-age=60", map[string]string{"/index.html": "home"})
+	s := newTestServer(t, Config{Origin: origin.URL})
+	if rec := serve(s, "GET", "/missing.js"); rec.Code != http.StatusNotFound {
+		t.Errorf("got %v, want 404", rec.Code)
+	}
+}
+
+func TestOriginFallbackPages(t *testing.T) {
+	origin, _ := newTestOrigin(t, "max-age=60", map[string]string{
+		"/index.html": "home",
+		"/404.html":   "not found",
+	})
+	s := newTestServer(t, Config{
+		Path:             "/nonexistent",
+		Origin:           origin.URL,
+		Fallback:         "/index.html",
+		FallbackPrefixes: []string{"/app/"},
+		NotFoundPage:     "/404.html",
+	})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/", http.StatusOK, "home"},
+		{"/app/deep/link", http.StatusOK, "home"},
+		{"/other.js", http.StatusNotFound, "not found"},
+	}
+	for _, tt := range tests {
+		rec := serve(s, "GET", tt.path)
+		if rec.Code != tt.wantStatus || rec.Body.String() != tt.wantBody {
+			t.Errorf("%v: got %v %q, want %v %q", tt.path, rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+		}
+	}
+}
+
+func TestOriginStaleWhileRevalidate(t *testing.T) {
+	var fetches int64
+	var version int64 = 1
+	release := make(chan struct{})
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&fetches, 1) > 1 {
+			// the revalidation is slow
+			<-release
+		}
+		_, _ = fmt.Fprintf(w, "v%v", atomic.LoadInt64(&version))
+	}))
+	defer origin.Close()
+
+	ttl := 50 * time.Millisecond
+	s := newTestServer(t, Config{
+		Origin:               origin.URL,
+		OriginCacheTTL:       ttl,
+		StaleWhileRevalidate: time.Minute,
+		DisableCompression:   true,
+	})
+	if rec := serve(s, "GET", "/app.js"); rec.Body.String() != "v1" {
+		t.Fatalf("got %q, want v1", rec.Body.String())
+	}
+	atomic.StoreInt64(&version, 2)
+	time.Sleep(2 * ttl)
+
+	// the expired file is served right away, while a single revalidation
+	// waits for the origin
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rec := serve(s, "GET", "/app.js"); rec.Code != http.StatusOK || rec.Body.String() != "v1" {
+				t.Errorf("got %v %q, want the stale v1", rec.Code, rec.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+	close(release)
+
+	for i := 0; serve(s, "GET", "/app.js").Body.String() != "v2"; i++ {
+		if i == 100 {
+			t.Fatal("file not revalidated in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := atomic.LoadInt64(&fetches); got != 2 {
+		t.Errorf("origin fetched %v times, want 2", got)
+	}
+}
+
+func TestOriginExpiredWithoutStaleWhileRevalidate(t *testing.T) {
+	var version int64 = 1
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "v%v", atomic.LoadInt64(&version))
+	}))
+	defer origin.Close()
+
+	ttl := 50 * time.Millisecond
+	s := newTestServer(t, Config{Origin: origin.URL, OriginCacheTTL: ttl, DisableCompression: true})
+	serve(s, "GET", "/app.js")
+	atomic.StoreInt64(&version, 2)
+	time.Sleep(2 * ttl)
+	if rec := serve(s, "GET", "/app.js"); rec.Body.String() != "v2" {
+		t.Errorf("got %q, want the fetched again v2", rec.Body.String())
+	}
+}
@@ -0,0 +1,103 @@
+package gostatic
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// precompressedExtensions are the extensions of the precompressed siblings
+// of a file, by content coding
+var precompressedExtensions = map[string]string{
+	"br":   ".br",
+	"gzip": ".gz",
+}
+
+// fileSizeInFS returns the size of a file, ok is false when it can't be
+// opened or is a directory
+func fileSizeInFS(fs http.FileSystem, name string) (size int64, ok bool) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return 0, false
+	}
+	return info.Size(), true
+}
+
+// precompressedWriter sets the Content-Length of a precompressed sibling,
+// which http.ServeContent leaves out of the responses having a
+// Content-Encoding
+type precompressedWriter struct {
+	http.ResponseWriter
+	size        int64
+	wroteHeader bool
+}
+
+func (w *precompressedWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status == http.StatusOK && w.Header().Get("Content-Length") == "" {
+			w.Header().Set("Content-Length", strconv.FormatInt(w.size, 10))
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *precompressedWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// precompressedMiddleware serves the precompressed sibling of a file, e.g.
+// app.js.gz for app.js, to the clients accepting its content coding. The
+// Content-Type is the one of the original file. Without a sibling, the
+// file is served, or compressed on the fly, as usual. The siblings are
+// picked in the preference order of the content codings.
+func precompressedMiddleware(fs http.FileSystem, preference []string, addVary bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		name := path.Clean("/" + r.URL.Path)
+
+		var available []string
+		sizes := make(map[string]int64)
+		for _, encoding := range preference {
+			if size, ok := fileSizeInFS(fs, name+precompressedExtensions[encoding]); ok {
+				available = append(available, encoding)
+				sizes[encoding] = size
+			}
+		}
+		if len(available) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if addVary {
+			w.Header().Add("Vary", "Accept-Encoding")
+		}
+
+		encoding := negotiateEncoding(r, available)
+		if encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Encoding", encoding)
+		r.URL.Path = name + precompressedExtensions[encoding]
+		r.URL.RawPath = ""
+		next.ServeHTTP(&precompressedWriter{ResponseWriter: w, size: sizes[encoding]}, r)
+	})
+}
@@ -0,0 +1,25 @@
+package gostatic
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+)
+
+// redirect replies with a redirection to url. With --redirect-body, the
+// response carries a minimal HTML page linking to the target, for clients
+// and crawlers which expect a body.
+func (s *Server) redirect(w http.ResponseWriter, r *http.Request, url string, code int) {
+	if !s.cfg.RedirectBody {
+		http.Redirect(w, r, url, code)
+		return
+	}
+	w.Header().Set("Location", url)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	if r.Method != http.MethodHead {
+		escaped := html.EscapeString(url)
+		_, _ = fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head><title>%v %v</title></head>\n<body><p>Moved to <a href=\"%v\">%v</a>.</p></body></html>\n",
+			code, http.StatusText(code), escaped, escaped)
+	}
+}
@@ -0,0 +1,107 @@
+package gostatic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// RedirectConfigArray is the content of the redirects config file
+type RedirectConfigArray struct {
+	Redirects []RedirectConfig `json:"redirects"`
+}
+
+// RedirectConfig is a single redirect rule. Match is exact, the default,
+// prefix or regex. Status defaults to 301.
+type RedirectConfig struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Match  string `json:"match"`
+	Status int    `json:"status"`
+}
+
+// redirectRule is a parsed redirect rule
+type redirectRule struct {
+	RedirectConfig
+	re *regexp.Regexp
+}
+
+// target returns the URL to redirect requestPath to, or "" when the rule
+// doesn't match
+func (rule redirectRule) target(requestPath string) string {
+	switch rule.Match {
+	case "prefix":
+		if strings.HasPrefix(requestPath, rule.From) {
+			return rule.To + strings.TrimPrefix(requestPath, rule.From)
+		}
+	case "regex":
+		if m := rule.re.FindStringSubmatchIndex(requestPath); m != nil {
+			return string(rule.re.ExpandString(nil, rule.To, requestPath, m))
+		}
+	default:
+		if requestPath == rule.From {
+			return rule.To
+		}
+	}
+	return ""
+}
+
+// loadRedirectConfig reads and checks the rules of a redirects config file
+func loadRedirectConfig(file string) ([]redirectRule, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read redirects config: %v", err)
+	}
+	var config RedirectConfigArray
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid redirects config %v: %v", file, err)
+	}
+
+	var rules []redirectRule
+	for _, c := range config.Redirects {
+		rule := redirectRule{RedirectConfig: c}
+		if rule.From == "" || rule.To == "" {
+			return nil, fmt.Errorf("redirect rule %q -> %q needs both from and to", c.From, c.To)
+		}
+		switch rule.Status {
+		case 0:
+			rule.Status = http.StatusMovedPermanently
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		default:
+			return nil, fmt.Errorf("redirect rule %q: invalid status %v, must be 301, 302, 307 or 308", c.From, c.Status)
+		}
+		switch rule.Match {
+		case "", "exact", "prefix":
+		case "regex":
+			if rule.re, err = regexp.Compile(rule.From); err != nil {
+				return nil, fmt.Errorf("redirect rule %q: %v", c.From, err)
+			}
+		default:
+			return nil, fmt.Errorf("redirect rule %q: invalid match %q, must be exact, prefix or regex", c.From, c.Match)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// redirectMiddleware redirects the requests matching a rule, the first
+// one winning. The query string is kept when the target has none.
+func (s *Server) redirectMiddleware(rules []redirectRule, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, rule := range rules {
+			target := rule.target(r.URL.Path)
+			if target == "" {
+				continue
+			}
+			if r.URL.RawQuery != "" && !strings.Contains(target, "?") {
+				target += "?" + r.URL.RawQuery
+			}
+			s.redirect(w, r, target, rule.Status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,43 @@
+package gostatic
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// lowVariantPath returns the path of the low quality variant of a file,
+// e.g. /img/photo.low.jpg for /img/photo.jpg
+func lowVariantPath(requestPath string) string {
+	ext := path.Ext(requestPath)
+	if ext == "" {
+		return ""
+	}
+	return strings.TrimSuffix(requestPath, ext) + ".low" + ext
+}
+
+func fileExistsInFS(fs http.FileSystem, name string) bool {
+	f, err := fs.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	return err == nil && !info.IsDir()
+}
+
+// saveDataMiddleware serves the low quality variant of a file, when one
+// exists, to clients sending the Save-Data: on client hint
+func saveDataMiddleware(fs http.FileSystem, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		variant := lowVariantPath(r.URL.Path)
+		if variant != "" && fileExistsInFS(fs, variant) {
+			w.Header().Add("Vary", "Save-Data")
+			if strings.EqualFold(strings.TrimSpace(r.Header.Get("Save-Data")), "on") {
+				r.URL.Path = variant
+				r.URL.RawPath = ""
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,50 @@
+package gostatic
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// runSelfTest requests the given paths on the listening server, and fails
+// on any error or unexpected status. Redirects and authentication
+// challenges are fine since they show the server is up. The certificate
+// isn't checked over TLS, it doesn't have to be valid for 127.0.0.1.
+// Unix domain sockets are requested with the localhost host.
+func runSelfTest(addr net.Addr, scheme string, paths []string) error {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	host := "localhost"
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		host = "127.0.0.1:" + strconv.Itoa(tcpAddr.Port)
+	} else {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, addr.Network(), addr.String())
+		}
+	}
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	for _, path := range paths {
+		resp, err := client.Get(scheme + "://" + host + path)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 && resp.StatusCode != http.StatusUnauthorized {
+			return fmt.Errorf("%v returned %v", path, resp.StatusCode)
+		}
+	}
+	return nil
+}
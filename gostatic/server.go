@@ -0,0 +1,211 @@
+package gostatic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+func checkTLSConfig(cfg Config) error {
+	if (cfg.Cert == "") != (cfg.Key == "") {
+		return errors.New("--cert and --key must be set together")
+	}
+	if cfg.HTTPRedirectPort > 0 && cfg.Cert == "" {
+		return errors.New("--http-redirect-port needs --cert and --key")
+	}
+	// the redirects go to the first HTTPS port, there is none on a socket
+	if cfg.HTTPRedirectPort > 0 && (len(cfg.UnixSocket) > 0 || len(cfg.Ports) == 0) {
+		return errors.New("--http-redirect-port needs HTTPS served on a --port, not a --unix-socket")
+	}
+	return nil
+}
+
+func (s *Server) listen(port int) (net.Listener, error) {
+	ln, err := listenTCP(":"+strconv.Itoa(port), s.cfg.TCPKeepAlive)
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return nil, fmt.Errorf("port %v is already in use; set --port or stop the conflicting process", port)
+	}
+	return ln, err
+}
+
+// newHTTPServer returns a server with the configured timeouts, so slow or
+// idle clients can't hold connections forever
+func (s *Server) newHTTPServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:      handler,
+		ReadTimeout:  s.cfg.ReadTimeout,
+		WriteTimeout: s.cfg.WriteTimeout,
+		IdleTimeout:  s.cfg.IdleTimeout,
+	}
+}
+
+// httpsRedirectHandler redirects every request to HTTPS on tlsPort
+func (s *Server) httpsRedirectHandler(tlsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := stripPort(s.requestHost(r))
+		if tlsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(tlsPort))
+		}
+		s.redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// listenAndServe serves handler on every port until one of the servers
+// fails or a termination signal is received. All the servers are then shut
+// down gracefully. With a certificate, the ports serve HTTPS, and the optional HTTP redirect port
+// only redirects to the first of them.
+func (s *Server) listenAndServe(handler http.Handler) error {
+	cfg := s.cfg
+	if len(cfg.Ports) == 0 && len(cfg.UnixSocket) == 0 {
+		return errors.New("no port to listen on")
+	}
+	// done stops the background goroutines once the servers are shut down
+	done := make(chan struct{})
+	defer close(done)
+
+	useTLS := cfg.Cert != ""
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+
+	var tlsConfig *tls.Config
+	if useTLS {
+		var err error
+		if tlsConfig, err = s.newTLSConfig(done); err != nil {
+			return err
+		}
+	}
+
+	var listeners []net.Listener
+	closeListeners := func() {
+		for _, ln := range listeners {
+			ln.Close()
+		}
+	}
+	if len(cfg.UnixSocket) > 0 {
+		ln, err := listenUnix(cfg.UnixSocket)
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, ln)
+	} else {
+		for _, port := range cfg.Ports {
+			ln, err := s.listen(port)
+			if err != nil {
+				closeListeners()
+				return err
+			}
+			listeners = append(listeners, ln)
+		}
+	}
+
+	errs := make(chan error, len(listeners)+2)
+	if cfg.SelfTest {
+		go func() {
+			paths := []string{s.pathPrefix}
+			if cfg.EnableHealth {
+				paths = append(paths, cfg.HealthPath)
+			}
+			for _, ln := range listeners {
+				if err := runSelfTest(ln.Addr(), scheme, paths); err != nil {
+					errs <- fmt.Errorf("self-test failed: %v", err)
+					return
+				}
+			}
+			log.Println("Self-test passed")
+		}()
+	}
+
+	if cfg.EnableHealth {
+		s.watchReadinessSignals(done)
+	}
+
+	var servers []*http.Server
+	if useTLS && cfg.DisableHTTP2 {
+		log.Println("Serving HTTP/1.1 only, HTTP/2 is disabled")
+	} else if useTLS {
+		log.Println("Serving HTTP/2 and HTTP/1.1")
+	}
+
+	for _, ln := range listeners {
+		srv := s.newHTTPServer(handler)
+		if cfg.DisableHTTP2 {
+			// a non-nil empty map turns off the automatic HTTP/2 support
+			srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		}
+		servers = append(servers, srv)
+		if tcpAddr, ok := ln.Addr().(*net.TCPAddr); ok {
+			log.Printf("Listening at %v://0.0.0.0:%v %v...", scheme, tcpAddr.Port, s.pathPrefix)
+		} else {
+			log.Printf("Listening at %v on %v %v...", scheme, ln.Addr(), s.pathPrefix)
+		}
+		go func(ln net.Listener) {
+			if useTLS {
+				ln = tls.NewListener(ln, tlsConfig)
+			}
+			errs <- srv.Serve(ln)
+		}(ln)
+	}
+
+	if useTLS && cfg.HTTPRedirectPort > 0 {
+		ln, err := s.listen(cfg.HTTPRedirectPort)
+		if err != nil {
+			errs <- err
+		} else {
+			var redirectHandler http.Handler = s.httpsRedirectHandler(cfg.Ports[0])
+			if len(cfg.DefaultHost) > 0 {
+				redirectHandler = defaultHostMiddleware(cfg.DefaultHost, redirectHandler)
+			}
+			srv := s.newHTTPServer(redirectHandler)
+			servers = append(servers, srv)
+			log.Printf("Redirecting http://0.0.0.0:%v to HTTPS...", cfg.HTTPRedirectPort)
+			go func() {
+				errs <- srv.Serve(ln)
+			}()
+		}
+	}
+
+	signal.Notify(s.stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(s.stop)
+
+	var serveErr error
+	select {
+	case serveErr = <-errs:
+	case sig := <-s.stop:
+		log.Println("Received " + sig.String() + ", shutting down")
+	}
+
+	// in-flight health checks fail from now on. The servers keep serving
+	// for the drain timeout, while the load balancers notice it.
+	s.setReady(false)
+	if serveErr == nil && cfg.DrainTimeout > 0 {
+		log.Printf("Draining connections for %v", cfg.DrainTimeout)
+		time.Sleep(cfg.DrainTimeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Println("Shutdown error:", err)
+			// the requests still running are cut
+			srv.Close()
+		}
+	}
+	if len(cfg.UnixSocket) > 0 {
+		// closing the listener already removes it, unless serving failed early
+		if err := os.Remove(cfg.UnixSocket); err != nil && !os.IsNotExist(err) {
+			log.Println("Unable to remove the socket:", err)
+		}
+	}
+	return serveErr
+}
@@ -0,0 +1,173 @@
+package gostatic
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// freePort returns a TCP port nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// startServer runs ListenAndServe in the background, and waits until the
+// server answers client at url. The returned channel gets its result.
+func startServer(t *testing.T, s *Server, client *http.Client, url string) <-chan error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServe()
+	}()
+	for i := 0; ; i++ {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			return done
+		}
+		if i == 100 {
+			t.Fatalf("server not answering at %v: %v", url, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// stopServer delivers a SIGTERM to the server and waits for it to return
+func stopServer(t *testing.T, s *Server, done <-chan error) {
+	t.Helper()
+	s.stop <- syscall.SIGTERM
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ListenAndServe: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server not stopped")
+	}
+}
+
+func TestListenOnSeveralPorts(t *testing.T) {
+	ports := []int{freePort(t), freePort(t)}
+	s := newTestServer(t, Config{
+		Path:  writeFiles(t, map[string]string{"a.txt": "same content"}),
+		Ports: ports,
+	})
+	// without keep-alive, no idle connection delays the shutdown
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	done := startServer(t, s, client, fmt.Sprintf("http://127.0.0.1:%v/", ports[0]))
+
+	for _, port := range ports {
+		resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%v/a.txt", port))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != "same content" {
+			t.Errorf("port %v: got %v %q, want 200 \"same content\"", port, resp.StatusCode, body)
+		}
+	}
+
+	stopServer(t, s, done)
+	for _, port := range ports {
+		if _, err := client.Get(fmt.Sprintf("http://127.0.0.1:%v/a.txt", port)); err == nil {
+			t.Errorf("port %v still served after the shutdown", port)
+		}
+	}
+}
+
+func TestDrainThenShutdown(t *testing.T) {
+	drain, shutdown := 300*time.Millisecond, 300*time.Millisecond
+	port := freePort(t)
+	s := newTestServer(t, Config{
+		Path:            writeFiles(t, map[string]string{"a.txt": "a"}),
+		Ports:           []int{port},
+		EnableHealth:    true,
+		DrainTimeout:    drain,
+		ShutdownTimeout: shutdown,
+	})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.NewServeMux()
+	handler.Handle("/", s.mux)
+	handler.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+
+	base := fmt.Sprintf("http://127.0.0.1:%v", port)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	done := make(chan error, 1)
+	go func() {
+		done <- s.listenAndServe(handler)
+	}()
+	for i := 0; ; i++ {
+		if resp, err := client.Get(base + "/health"); err == nil {
+			resp.Body.Close()
+			break
+		}
+		if i == 100 {
+			t.Fatal("server not answering")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	s.stop <- syscall.SIGTERM
+	slowErr := make(chan error, 1)
+	go func() {
+		resp, err := client.Get(base + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		slowErr <- err
+	}()
+
+	// draining: the health check fails, the files are still served
+	time.Sleep(drain / 3)
+	resp, err := client.Get(base + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("health check while draining: got %v, want 503", resp.StatusCode)
+	}
+	resp, err = client.Get(base + "/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("file while draining: got %v, want 200", resp.StatusCode)
+	}
+
+	// shutting down: the listeners are closed, the slow request has until
+	// the shutdown timeout to complete
+	time.Sleep(drain - drain/3 + shutdown/3)
+	if _, err := client.Get(base + "/a.txt"); err == nil {
+		t.Error("new connection accepted after the drain timeout")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server not stopped")
+	}
+	elapsed := time.Since(start)
+	if elapsed < drain+shutdown || elapsed > drain+shutdown+time.Second {
+		t.Errorf("stopped after %v, want about %v", elapsed, drain+shutdown)
+	}
+	if err := <-slowErr; err == nil {
+		t.Error("slow request completed, want its connection closed")
+	}
+}
@@ -0,0 +1,24 @@
+package gostatic
+
+import (
+	"net/http"
+	"os"
+	"path"
+)
+
+// spaMiddleware serves the fallback page with a 200 for every path missing
+// on disk, so a client-side router can handle deep links. Existing files
+// and directories are served as usual.
+func (s *Server) spaMiddleware(fs http.FileSystem, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, err := fs.Open(path.Clean("/" + r.URL.Path))
+		if err == nil {
+			f.Close()
+		}
+		if os.IsNotExist(err) {
+			s.serveDefaultPage(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,192 @@
+package gostatic
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	sriTagRegexp  = regexp.MustCompile(`(?is)<(script|link)\b[^>]*>`)
+	sriAttrRegexp = regexp.MustCompile(`(?is)\s(src|href|rel|integrity)\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+)
+
+// sriHash is the integrity of an asset, valid as long as the file keeps
+// its modification time and size
+type sriHash struct {
+	modTime   time.Time
+	size      int64
+	integrity string
+}
+
+// sriHashes computes and caches the integrity of local assets
+type sriHashes struct {
+	sync.RWMutex
+	fs     http.FileSystem
+	hashes map[string]sriHash
+}
+
+func newSRIHashes(fs http.FileSystem) *sriHashes {
+	return &sriHashes{fs: fs, hashes: make(map[string]sriHash)}
+}
+
+// integrity returns the sha384 integrity of the file, or "" if it can't be read
+func (h *sriHashes) integrity(name string) string {
+	f, err := h.fs.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return ""
+	}
+
+	h.RLock()
+	cached, ok := h.hashes[name]
+	h.RUnlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.integrity
+	}
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return ""
+	}
+	sum := sha512.Sum384(data)
+	integrity := "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+
+	h.Lock()
+	h.hashes[name] = sriHash{modTime: info.ModTime(), size: info.Size(), integrity: integrity}
+	h.Unlock()
+	return integrity
+}
+
+// localAssetPath resolves the URL of an asset referenced by the page at
+// pagePath to a path of the file system, or "" for other origins
+func localAssetPath(ref, pagePath, prefix string) string {
+	if ref == "" || strings.HasPrefix(ref, "//") || strings.Contains(ref, ":") {
+		return ""
+	}
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		ref = ref[:i]
+	}
+	if strings.HasPrefix(ref, "/") {
+		// absolute URLs include the context, the page path doesn't
+		if !strings.HasPrefix(ref, prefix) {
+			return ""
+		}
+		return path.Clean("/" + strings.TrimPrefix(ref, prefix))
+	}
+	dir := pagePath
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir)
+	}
+	return path.Clean(path.Join("/", dir, ref))
+}
+
+// injectSRI adds an integrity attribute to the scripts and stylesheets of
+// the page served at pagePath which reference local assets
+func injectSRI(page []byte, pagePath, prefix string, hashes *sriHashes) []byte {
+	return sriTagRegexp.ReplaceAllFunc(page, func(tag []byte) []byte {
+		attrs := make(map[string]string)
+		for _, m := range sriAttrRegexp.FindAllSubmatch(tag, -1) {
+			attrs[strings.ToLower(string(m[1]))] = strings.Trim(string(m[2]), `"'`)
+		}
+		if _, ok := attrs["integrity"]; ok {
+			return tag
+		}
+		ref := attrs["src"]
+		if bytes.EqualFold(tag[1:5], []byte("link")) {
+			rel := strings.ToLower(attrs["rel"])
+			if !strings.Contains(rel, "stylesheet") && !strings.Contains(rel, "preload") {
+				return tag
+			}
+			ref = attrs["href"]
+		}
+		name := localAssetPath(ref, pagePath, prefix)
+		if name == "" {
+			return tag
+		}
+		integrity := hashes.integrity(name)
+		if integrity == "" {
+			return tag
+		}
+		end := len(tag) - 1
+		if tag[end-1] == '/' {
+			end--
+		}
+		injected := append([]byte{}, tag[:end]...)
+		injected = append(injected, ` integrity="`+integrity+`"`...)
+		return append(injected, tag[end:]...)
+	})
+}
+
+// sriResponseWriter buffers successful HTML responses to rewrite them once
+// complete. Other responses are passed through.
+type sriResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	buf         *bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *sriResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if status != http.StatusOK || mediaType != "text/html" || w.Header().Get("Content-Encoding") != "" {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+	w.buf = new(bytes.Buffer)
+}
+
+func (w *sriResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buf != nil {
+		return w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// sriMiddleware injects Subresource Integrity hashes in the HTML pages.
+// The validators of the rewritten pages are dropped: they would stay the
+// same when only an asset changes, and clients would keep stale hashes.
+func sriMiddleware(fs http.FileSystem, prefix string, next http.Handler) http.Handler {
+	hashes := newSRIHashes(fs)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+		sw := &sriResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.buf == nil {
+			return
+		}
+		page := injectSRI(sw.buf.Bytes(), "/"+strings.TrimPrefix(r.URL.Path, "/"), prefix, hashes)
+		w.Header().Del("ETag")
+		w.Header().Del("Last-Modified")
+		w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+		w.WriteHeader(sw.status)
+		_, _ = w.Write(page)
+	})
+}
@@ -0,0 +1,126 @@
+package gostatic
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// maxStaleEntry is the largest file kept to be served stale
+const maxStaleEntry = 1 << 20
+
+// staleFile is the last content read from a file, checked is when the
+// file was last opened without error
+type staleFile struct {
+	content []byte
+	modTime time.Time
+	checked time.Time
+}
+
+// staleCache keeps the content of small files, to serve them while the
+// disk fails, e.g. during a network file system hiccup. A file is served
+// stale up to maxAge after it was last opened.
+type staleCache struct {
+	entries *boundedCache
+	maxAge  time.Duration
+}
+
+func newStaleCache(maxSize int, maxAge time.Duration) *staleCache {
+	return &staleCache{entries: newBoundedCache(maxSize), maxAge: maxAge}
+}
+
+func (c *staleCache) get(name string) (staleFile, bool) {
+	entry, ok := c.entries.get(name)
+	if !ok {
+		return staleFile{}, false
+	}
+	return entry.(staleFile), true
+}
+
+func (c *staleCache) add(name string, entry staleFile) {
+	c.entries.add(name, entry, len(entry.content))
+}
+
+// staleFS keeps the content of the small files opened in the cache. A
+// file is only read again once its modification time or size changes.
+type staleFS struct {
+	fs    http.FileSystem
+	cache *staleCache
+}
+
+func (sfs staleFS) Open(name string) (http.File, error) {
+	f, err := sfs.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil || info.IsDir() || info.Size() > maxStaleEntry {
+		return f, nil
+	}
+	if entry, ok := sfs.cache.get(name); ok && entry.modTime.Equal(info.ModTime()) && int64(len(entry.content)) == info.Size() {
+		entry.checked = time.Now()
+		sfs.cache.add(name, entry)
+		return f, nil
+	}
+	content, err := ioutil.ReadAll(f)
+	if err == nil {
+		sfs.cache.add(name, staleFile{content: content, modTime: info.ModTime(), checked: time.Now()})
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return sfs.fs.Open(name)
+	}
+	return f, nil
+}
+
+// staleResponseWriter holds back the 500 responses of files having a
+// cached content
+type staleResponseWriter struct {
+	http.ResponseWriter
+	cached      bool
+	intercepted bool
+}
+
+func (w *staleResponseWriter) WriteHeader(status int) {
+	if status == http.StatusInternalServerError && w.cached {
+		w.intercepted = true
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *staleResponseWriter) Write(b []byte) (int, error) {
+	if w.intercepted {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// staleMiddleware serves the cached content of a file, with a
+// Warning: 110 header, when reading it from disk fails and it was last
+// opened less than the max age of the cache ago
+func staleMiddleware(cache *staleCache, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/") {
+			name = path.Join(name, "index.html")
+		}
+		entry, ok := cache.get(name)
+		ok = ok && time.Since(entry.checked) <= cache.maxAge
+
+		sw := &staleResponseWriter{ResponseWriter: w, cached: ok}
+		next.ServeHTTP(sw, r)
+		if !sw.intercepted {
+			return
+		}
+
+		w.Header().Del("Content-Type")
+		w.Header().Del("X-Content-Type-Options")
+		w.Header().Set("Warning", `110 - "Response is Stale"`)
+		http.ServeContent(w, r, name, entry.modTime, bytes.NewReader(entry.content))
+	})
+}
@@ -0,0 +1,79 @@
+package gostatic
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// breakFile makes opening name fail with an error other than not found or
+// permission denied, by replacing it with a symlink to itself
+func breakFile(t *testing.T, name string) {
+	t.Helper()
+	if err := os.Remove(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(name, name); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServeStale(t *testing.T) {
+	tests := []struct {
+		name       string
+		fail       func(t *testing.T, name string)
+		wantStatus int
+		wantStale  bool
+	}{
+		{"read failure", breakFile, http.StatusOK, true},
+		{"removed", func(t *testing.T, name string) { os.Remove(name) }, http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"a.txt": "cached content"})
+			s := newTestServer(t, Config{Path: dir, ServeStale: true})
+			if rec := serve(s, "GET", "/a.txt"); rec.Code != http.StatusOK || rec.Header().Get("Warning") != "" {
+				t.Fatalf("got %v, Warning %q, want a fresh 200", rec.Code, rec.Header().Get("Warning"))
+			}
+
+			tt.fail(t, filepath.Join(dir, "a.txt"))
+			rec := serve(s, "GET", "/a.txt")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if !tt.wantStale {
+				if got := rec.Header().Get("Warning"); got != "" {
+					t.Errorf("Warning %q on a response that isn't stale", got)
+				}
+				return
+			}
+			if got := rec.Header().Get("Warning"); got != `110 - "Response is Stale"` {
+				t.Errorf("Warning %q", got)
+			}
+			if got := rec.Body.String(); got != "cached content" {
+				t.Errorf("body %q, want the cached content", got)
+			}
+		})
+	}
+}
+
+func TestServeStaleMaxAge(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "cached content"})
+	s := newTestServer(t, Config{Path: dir, ServeStale: true, ServeStaleMaxAge: 100 * time.Millisecond})
+	serve(s, "GET", "/a.txt")
+	breakFile(t, filepath.Join(dir, "a.txt"))
+
+	if rec := serve(s, "GET", "/a.txt"); rec.Code != http.StatusOK || rec.Header().Get("Warning") == "" {
+		t.Fatalf("within the max age: got %v, Warning %q, want a stale 200", rec.Code, rec.Header().Get("Warning"))
+	}
+	time.Sleep(150 * time.Millisecond)
+	rec := serve(s, "GET", "/a.txt")
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("past the max age: status %v, want %v", rec.Code, http.StatusInternalServerError)
+	}
+	if got := rec.Header().Get("Warning"); got != "" {
+		t.Errorf("past the max age: Warning %q", got)
+	}
+}
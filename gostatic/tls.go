@@ -0,0 +1,70 @@
+package gostatic
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"log"
+	"time"
+)
+
+// newTLSConfig loads the certificate and key of the server. The config is
+// shared by all the listeners, so the session ticket keys can be rotated
+// in one place, until done is closed.
+func (s *Server) newTLSConfig(done <-chan struct{}) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(s.cfg.Cert, s.cfg.Key)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if s.cfg.DisableHTTP2 {
+		config.NextProtos = []string{"http/1.1"}
+	}
+	if s.cfg.TLSTicketRotation > 0 {
+		if err := rotateSessionTicketKeys(config, s.cfg.TLSTicketRotation, done); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func newSessionTicketKey() ([32]byte, error) {
+	var key [32]byte
+	_, err := rand.Read(key[:])
+	return key, err
+}
+
+// rotateSessionTicketKeys replaces the session ticket key every interval.
+// Once a key is dropped, the sessions it protected can't be decrypted
+// anymore, even if the server is later compromised. The previous key is
+// kept one more interval so recently issued tickets still resume. The
+// rotation stops once done is closed.
+func rotateSessionTicketKeys(config *tls.Config, interval time.Duration, done <-chan struct{}) error {
+	current, err := newSessionTicketKey()
+	if err != nil {
+		return err
+	}
+	config.SetSessionTicketKeys([][32]byte{current})
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+			next, err := newSessionTicketKey()
+			if err != nil {
+				log.Println("Can't rotate the TLS session ticket key:", err)
+				continue
+			}
+			config.SetSessionTicketKeys([][32]byte{next, current})
+			current = next
+		}
+	}()
+	return nil
+}
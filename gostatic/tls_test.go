@@ -0,0 +1,199 @@
+package gostatic
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self-signed certificate for 127.0.0.1 and its key,
+// and returns their paths
+func writeTestCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestDisableHTTP2(t *testing.T) {
+	certFile, keyFile := writeTestCert(t)
+	tests := []struct {
+		disableHTTP2 bool
+		wantProto    string
+	}{
+		{false, "HTTP/2.0"},
+		{true, "HTTP/1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantProto, func(t *testing.T) {
+			port := freePort(t)
+			s := newTestServer(t, Config{
+				Path:         writeFiles(t, map[string]string{"a.txt": "a"}),
+				Ports:        []int{port},
+				Cert:         certFile,
+				Key:          keyFile,
+				DisableHTTP2: tt.disableHTTP2,
+			})
+			client := &http.Client{Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+				ForceAttemptHTTP2: true,
+			}}
+			url := fmt.Sprintf("https://127.0.0.1:%v/a.txt", port)
+			done := startServer(t, s, client, url)
+			defer stopServer(t, s, done)
+			// closed first, so no idle connection delays the shutdown
+			defer client.CloseIdleConnections()
+
+			resp, err := client.Get(url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.Proto != tt.wantProto {
+				t.Errorf("got %v, want %v", resp.Proto, tt.wantProto)
+			}
+			if got := resp.TLS.NegotiatedProtocol; tt.disableHTTP2 && got == "h2" {
+				t.Errorf("negotiated %q, want HTTP/1.1", got)
+			}
+		})
+	}
+}
+
+// resumes tells whether a new connection to addr resumes a session from
+// cache
+func resumes(t *testing.T, addr string, cache tls.ClientSessionCache) bool {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true, ClientSessionCache: cache})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// with TLS 1.3 the ticket is only received once reading
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	return conn.ConnectionState().DidResume
+}
+
+func TestTLSTicketRotation(t *testing.T) {
+	certFile, keyFile := writeTestCert(t)
+	interval := 100 * time.Millisecond
+	s := newTestServer(t, Config{
+		Path:              writeFiles(t, nil),
+		Cert:              certFile,
+		Key:               keyFile,
+		TLSTicketRotation: interval,
+	})
+	done := make(chan struct{})
+	stopped := false
+	defer func() {
+		if !stopped {
+			close(done)
+		}
+	}()
+	config, err := s.newTLSConfig(done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("x"))
+			conn.Close()
+		}
+	}()
+
+	addr := ln.Addr().String()
+	cache := tls.NewLRUClientSessionCache(1)
+	if resumes(t, addr, cache) {
+		t.Fatal("first connection resumed a session")
+	}
+	if !resumes(t, addr, cache) {
+		t.Error("session not resumed right away")
+	}
+	// the key of the ticket is kept one more interval after being replaced
+	time.Sleep(3 * interval)
+	if resumes(t, addr, cache) {
+		t.Error("session resumed once its ticket key was dropped")
+	}
+
+	// the last connection got a new ticket, which the keys stopped rotating
+	// keep valid
+	close(done)
+	stopped = true
+	time.Sleep(3 * interval)
+	if !resumes(t, addr, cache) {
+		t.Error("ticket keys still rotating once done was closed")
+	}
+}
+
+func TestHTTPRedirectPortNeedsAPort(t *testing.T) {
+	certFile, keyFile := writeTestCert(t)
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"port", Config{Ports: []int{8443}}, false},
+		{"unix socket", Config{UnixSocket: "/tmp/gostatic.sock", Ports: []int{8443}}, true},
+		{"no port", Config{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Path = writeFiles(t, nil)
+			tt.cfg.Cert, tt.cfg.Key = certFile, keyFile
+			tt.cfg.HTTPRedirectPort = 8080
+			if _, err := New(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want one: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// logOutputWriter is where the logs are written
+var logOutputWriter io.Writer = os.Stderr
+
+// setLogOutput redirects the default logger to stdout or stderr
+func setLogOutput(output string) error {
+	switch output {
+	case "stderr":
+		logOutputWriter = os.Stderr
+	case "stdout":
+		logOutputWriter = os.Stdout
+	default:
+		return fmt.Errorf("unknown log output %q, must be stdout or stderr", output)
+	}
+	log.SetOutput(logOutputWriter)
+	return nil
+}
+
+// timestampWriter prefixes each log line with its own timestamp
+type timestampWriter struct {
+	out      io.Writer
+	format   string
+	location *time.Location
+}
+
+func (w timestampWriter) Write(b []byte) (int, error) {
+	now := time.Now().In(w.location)
+	var stamp string
+	if w.format == "unix" {
+		stamp = strconv.FormatInt(now.Unix(), 10)
+	} else {
+		stamp = now.Format(w.format)
+	}
+	if _, err := w.out.Write(append([]byte(stamp+" "), b...)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// setLogTimeFormat changes the timestamp of log lines. format is either
+// default, rfc3339, unix, none or a Go time layout.
+func setLogTimeFormat(format string, timezone string) error {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return fmt.Errorf("unknown log timezone %q: %v", timezone, err)
+	}
+
+	switch format {
+	case "default":
+		if timezone == "Local" {
+			return nil
+		}
+		format = "2006/01/02 15:04:05"
+	case "none":
+		log.SetFlags(0)
+		return nil
+	case "rfc3339":
+		format = time.RFC3339
+	}
+
+	log.SetFlags(0)
+	log.SetOutput(timestampWriter{out: logOutputWriter, format: format, location: location})
+	return nil
+}
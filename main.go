@@ -4,217 +4,306 @@
 package main
 
 import (
-	"compress/gzip"
 	"flag"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"log"
-	"net/http"
-	"os"
-	"regexp"
 	"strconv"
 	"strings"
-	"sync"
+	"time"
+
+	"github.com/PierreZ/goStatic/gostatic"
 )
 
 var (
 	// Def of flags
 	portPtr                  = flag.Int("port", 1080, "The listening port")
-	context                  = flag.String("context", "", "The 'context' path on which files are served, e.g. 'doc' will serve the files at 'http://localhost:<port>/doc/'")
+	portsFlag                = flag.String("ports", "", "Comma separated listening ports, e.g. '80,8080', serving the same content. Overrides --port")
+	drainTimeout             = flag.Duration("drain-timeout", 0, "On shutdown, how long to keep serving with a failing health check before closing the listeners")
+	shutdownTimeout          = flag.Duration("shutdown-timeout", 10*time.Second, "On shutdown, how long in-flight requests have to complete before their connections are closed")
+	readTimeout              = flag.Duration("read-timeout", 15*time.Second, "Maximum duration to read a request, headers and body. 0 means no limit")
+	writeTimeout             = flag.Duration("write-timeout", 15*time.Second, "Maximum duration to write a response, from the end of the request headers. 0 means no limit")
+	idleTimeout              = flag.Duration("idle-timeout", 60*time.Second, "Maximum duration to wait for the next request on a keep-alive connection. 0 means the read timeout")
+	unixSocket               = flag.String("unix-socket", "", "Listen on this Unix domain socket instead of a TCP port")
+	cleanURLs                = flag.Bool("clean-urls", false, "Serve /about.html for /about, and redirect /about.html to /about")
+	cleanURLExtensions       = flag.String("clean-url-extensions", "", "Comma separated extensions tried for missing files, e.g. '.html,.htm' serves /about.html for /about")
+	corsAllowHeaders         = flag.String("cors-allow-headers", "", "Access-Control-Allow-Headers sent to preflight requests, e.g. 'Authorization, Content-Type'")
+	corsAllowMethods         = flag.String("cors-allow-methods", "GET, HEAD, OPTIONS", "Access-Control-Allow-Methods sent to preflight requests")
+	corsAllowOrigin          = flag.String("cors-allow-origin", "*", "Comma separated origins allowed to fetch the files, or * for any")
+	configFile               = flag.String("config", "", "JSON configuration file of flag names and values, or YAML with a build with the yaml tag. Flags and GOSTATIC_* environment variables take precedence")
+	contextFlag              = flag.String("context", "", "The 'context' path on which files are served, e.g. 'doc' will serve the files at 'http://localhost:<port>/doc/'")
+	contextRoot              = flag.String("context-root", "auto", "What the root of the context serves, either auto, index, fallback, listing or 404")
+	origin                   = flag.String("origin", "", "Fetch the files from this upstream URL instead of --path, e.g. 'https://cdn.example.com', keeping them in memory")
+	originCacheTTL           = flag.Duration("origin-cache-ttl", 0, "How long files fetched from the --origin are kept, overriding the upstream cache headers")
+	staleWhileRevalidate     = flag.Duration("stale-while-revalidate", 0, "How long an expired file from the --origin is still served while it is fetched again in the background")
 	basePath                 = flag.String("path", "/srv/http", "The path for the static files")
 	fallbackPath             = flag.String("fallback", "/index.html", "Default fallback file. Either absolute for a specific asset (/index.html), or relative to recursively resolve (index.html)")
 	headerFlag               = flag.String("append-header", "", "HTTP response header, specified as `HeaderName:Value` that should be added to all responses.")
 	basicAuth                = flag.Bool("enable-basic-auth", false, "Enable basic auth. By default, password are randomly generated. Use --set-basic-auth to set it.")
 	healthCheck              = flag.Bool("enable-health", false, "Enable health check endpoint. You can call /health to get a 200 response. Useful for Kubernetes, OpenFaas, etc.")
+	basicAuthFile            = flag.String("basic-auth-file", "", "Apache htpasswd file of the basic auth users, with bcrypt hashes. Needs a build with the bcrypt tag")
 	setBasicAuth             = flag.String("set-basic-auth", "", "Define the basic auth. Form must be user:password")
 	defaultUsernameBasicAuth = flag.String("default-user-basic-auth", "gopher", "Define the user")
 	sizeRandom               = flag.Int("password-length", 16, "Size of the randomized password")
 	logRequest               = flag.Bool("enable-logging", false, "Enable log request")
+	htmlNoCache              = flag.Bool("html-no-cache", false, "Send Cache-Control: no-cache, must-revalidate with HTML pages, whatever the other cache rules")
 	httpsPromote             = flag.Bool("https-promote", false, "All HTTP requests should be redirected to HTTPS")
+	geoIPDB                  = flag.String("geoip-db", "", "Path to a MaxMind GeoIP database used to add the client country and city to structured request logs. Needs a build with the geoip tag")
+	prestopGrace             = flag.Duration("prestop-grace", 0, "Enable the /admin/prestop endpoint, behind basic auth, which makes /health fail and waits this long before answering. For Kubernetes preStop hooks")
+	healthPath               = flag.String("health-path", "/health", "Path of the health check endpoint")
+	healthBody               = flag.String("health-body", "Ok", "Body of the successful health checks, served as JSON when valid, e.g. '{\"status\":\"ok\"}'")
+	healthMaxInFlight        = flag.Int("health-max-inflight", 0, "Fail the health check while more requests than this are being served. 0 means no limit")
+	healthOnly               = flag.Bool("health-only", false, "Serve no files, only the health endpoint and a health summary at /")
+	gzipSkipPath             = flag.String("gzip-skip-path", "", "Regular expression of request paths which are never compressed, e.g. '^/downloads/'")
+	redirectBody             = flag.Bool("redirect-body", false, "Send a minimal HTML page linking to the target with redirect responses")
+	gzipSkipUA               = flag.String("gzip-skip-ua", "", "Regular expression of User-Agents which never get compressed responses, e.g. 'MSIE [1-6]\\.'")
+	noGzipHTTP2              = flag.Bool("no-gzip-http2", false, "Don't compress the responses to HTTP/2 requests on the fly")
+	disableCompression       = flag.Bool("disable-compression", false, "Never compress responses")
+	noCompressSetVary        = flag.Bool("no-compress-set-vary", false, "Never compress responses but still send Vary: Accept-Encoding, for CDNs compressing at the edge")
+	redirectsConfig          = flag.String("redirects-config", "", "Path to a JSON file of redirect rules, matching the request paths exactly, by prefix or by regular expression")
 	headerConfigPath         = flag.String("header-config-path", "/config/headerConfig.json", "Path to the config file for custom response headers")
-
-	username string
-	password string
-
-	defaultPageBytes []byte
+	allowGzipLevelOverride   = flag.Bool("allow-gzip-level-override", false, "Let trusted clients pick the gzip level of a response with the X-Gzip-Level header, from -2 to 9")
+	gzipLevel                = flag.Int("gzip-level", 6, "The gzip compression level, from 1 (fastest) to 9 (smallest)")
+	gzipTypesFlag            = flag.String("gzip-types", gostatic.DefaultGzipTypes, "Comma separated extensions of the files to compress, '*' for all of them")
+	gzipListingsOnly         = flag.Bool("gzip-listings-only", false, "Only compress the directory listings, serving the files as is")
+	gzipMinSize              = flag.Int("gzip-min-size", 1024, "Responses smaller than this size in bytes are not compressed")
+	gzipLevelOverrideFrom    = flag.String("gzip-level-override-from", "127.0.0.1,::1", "With --allow-gzip-level-override, comma separated IPs or CIDRs of the trusted clients")
+	cacheCompressed          = flag.Bool("cache-compressed", false, "Keep the compressed output of small files in memory instead of compressing them on every request")
+	cacheDebugHeaders        = flag.Bool("cache-debug-headers", false, "Send X-Cache and Age headers on the responses eligible to the compressed responses cache")
+	cacheCompressedSize      = flag.Int("cache-compressed-size", 16<<20, "Maximum size in bytes of the compressed responses cache")
+	maxFileSize              = flag.Int64("max-file-size", 0, "Files larger than this size in bytes are refused with a 403. 0 means no limit")
+	respectSaveData          = flag.Bool("respect-save-data", false, "Serve the low quality variant of a file (photo.low.jpg for photo.jpg), when it exists, to clients sending Save-Data: on")
+	canonicalHost            = flag.String("canonical-host", "", "Redirect requests made to any other host to this one, e.g. 'example.com'")
+	dateHeader               = flag.String("date-header", "auto", "Date response header, either auto for the current date or off to not send it")
+	fixedDate                = flag.String("fixed-date", "", "Constant Date response header, e.g. 'Mon, 02 Jan 2006 15:04:05 GMT'. Useful to test caching behaviours deterministically")
+	immutablePattern         = flag.String("immutable-pattern", "", "Regular expression of request paths served with Cache-Control: "+gostatic.ImmutableCacheControl+", e.g. '\\.[0-9a-f]{6,}\\.(js|css)$'")
+	defaultCacheControl      = flag.String("default-cache-control", "", "Cache-Control value of the responses without one from the header config")
+	defaultHost              = flag.String("default-host", "", "Host used for requests without a Host header, e.g. from HTTP/1.0 clients")
+	defaultFaviconFlag       = flag.Bool("default-favicon", false, "Serve a built-in transparent /favicon.ico when there is none on disk")
+	enableCORS               = flag.Bool("enable-cors", false, "Send CORS headers, and answer preflight requests with a 204")
+	enableETag               = flag.Bool("enable-etag", false, "Send an ETag with served files, and answer 304 Not Modified to requests with a matching If-None-Match")
+	etagVersion              = flag.String("etag-version", "", "Send this deploy version as the ETag of every file, instead of one per file. Implies --enable-etag")
+	enableManifest           = flag.Bool("enable-manifest", false, "Serve a JSON manifest listing all the served files, generated at startup")
+	manifestPath             = flag.String("manifest-path", "/_manifest.json", "Path of the JSON manifest")
+	manifestFields           = flag.String("manifest-fields", "size,hash", "Comma separated fields listed for each file of the manifest, among size, hash and modified")
+	emptyRootMessage         = flag.String("empty-root-message", "", "Message served at the root while the static files path is empty, e.g. 'goStatic is running but has no content yet'")
+	failOnMissingFallback    = flag.Bool("fail-on-missing-fallback", true, "Exit when the fallback file doesn't exist. When false, the fallback is disabled with a warning instead")
+	forbiddenPage            = flag.String("forbidden-page", "", "Custom page served for 403 responses, relative to the static files path, e.g. '/403.html'")
+	strictSubstitution       = flag.Bool("strict-substitution", false, "Fail to start when a variable to replace isn't found in the fallback page")
+	notFoundPage             = flag.String("not-found-page", "", "Custom page served for 404 responses, relative to the static files path, e.g. '/404.html'")
+	logFormat                = flag.String("log-format", "text", "Format of the request logs, either text, logfmt, common, combined or json")
+	logFieldsFlag            = flag.String("log-fields", "method,path,status,bytes,duration,remote_addr", "Comma separated fields of structured request logs, among method, path, query, host, proto, status, bytes, duration, remote_addr, user_agent, referer, request_id, country and city")
+	logAsync                 = flag.Bool("log-async", false, "Write logs from a goroutine, so requests don't wait for the log output")
+	logAsyncBuffer           = flag.Int("log-async-buffer", 1024, "With --log-async, number of log lines buffered")
+	logAsyncDrop             = flag.Bool("log-async-drop", false, "With --log-async, drop log lines when the buffer is full instead of waiting")
+	logTimeFormat            = flag.String("log-time-format", "default", "Timestamp of log lines, either default, rfc3339, unix, none or a Go time layout")
+	logTimezone              = flag.String("log-timezone", "Local", "Timezone of log timestamps, e.g. UTC or Europe/Paris")
+	logHeaders               = flag.Bool("log-headers", false, "Debug option logging the request and response headers, with credentials redacted")
+	logOutput                = flag.String("log-output", "stderr", "Where logs are written, either stdout or stderr")
+	selfTest                 = flag.Bool("self-test", false, "Request / (and /health when enabled) once listening, and exit with an error if it fails")
+	verboseStartup           = flag.Bool("verbose-startup", false, "Log the middlewares requests go through, in order, at startup")
+	certFile                 = flag.String("cert", "", "Path to the TLS certificate, to serve HTTPS directly. Needs --key")
+	injectSRIFlag            = flag.Bool("inject-sri", false, "Add Subresource Integrity hashes to the local scripts and stylesheets referenced by HTML pages")
+	keyFile                  = flag.String("key", "", "Path to the TLS private key. Needs --cert")
+	disableBrotli            = flag.Bool("disable-brotli", false, "Only compress with gzip, even when brotli support is built in")
+	encodingPreference       = flag.String("encoding-preference", "br,gzip", "Content codings by preference, picked between those a client accepts with the same quality value")
+	servePrecompressed       = flag.Bool("serve-precompressed", false, "Serve the .br or .gz sibling of a file, e.g. app.js.gz for app.js, to the clients accepting its encoding")
+	rejectDoubleEncoding     = flag.Bool("reject-double-encoding", false, "Answer 400 to requests whose path is still percent-encoded once decoded, e.g. %252e%252e")
+	serveStale               = flag.Bool("serve-stale", false, "Keep small files in memory, and serve them with a Warning: 110 header while reading them from disk fails")
+	serveStaleSize           = flag.Int("serve-stale-size", 64<<20, "Maximum size in bytes of the files kept for --serve-stale")
+	serveStaleMaxAge         = flag.Duration("serve-stale-max-age", time.Hour, "How long after a file was last opened --serve-stale keeps serving it")
+	spa                      = flag.Bool("spa", false, "Single-page app mode: serve the fallback page with a 200 for every path missing on disk")
+	tlsTicketRotation        = flag.Duration("tls-ticket-rotation", 0, "With TLS, interval at which the session ticket key is replaced, e.g. 1h. 0 keeps the key of the process lifetime")
+	disableDirectoryListing  = flag.Bool("disable-directory-listing", false, "Answer 404, or serve the fallback, for directories without an index.html instead of listing their files")
+	disableHTTP2             = flag.Bool("disable-http2", false, "With TLS, only serve HTTP/1.1")
+	httpRedirectPort         = flag.Int("http-redirect-port", 0, "With TLS, plain HTTP port only redirecting to HTTPS")
+	trustXForwardedHost      = flag.Bool("trust-x-forwarded-host", false, "Build redirects with the host from the last X-Forwarded-Host entry, when behind a proxy")
+	trustedProxies           = flag.String("trusted-proxies", "", "Comma separated IPs or CIDRs of the proxies whose X-Forwarded-For and X-Forwarded-Host are trusted. Required by --trust-forwarded-for and --trust-x-forwarded-host")
+	trustForwardedFor        = flag.Bool("trust-forwarded-for", false, "Take the client IP from the last X-Forwarded-For entry, when behind a proxy")
+	tcpKeepAlive             = flag.Duration("tcp-keepalive", 3*time.Minute, "TCP keep-alive period for accepted connections. 0 disables keep-alive")
+
+	queryHeaders     stringsFlag
+	fallbackPrefixes stringsFlag
+	authPaths        stringsFlag
+	allowIPs         stringsFlag
+	denyIPs          stringsFlag
 )
 
-func parseHeaderFlag(headerFlag string) (string, string) {
-	if len(headerFlag) == 0 {
-		return "", ""
-	}
-	pieces := strings.SplitN(headerFlag, ":", 2)
-	if len(pieces) == 1 {
-		return pieces[0], ""
-	}
-	return pieces[0], pieces[1]
-}
-
-var gzPool = sync.Pool{
-	New: func() interface{} {
-		w := gzip.NewWriter(ioutil.Discard)
-		return w
-	},
-}
-
-type gzipResponseWriter struct {
-	io.Writer
-	http.ResponseWriter
-}
-
-func (w *gzipResponseWriter) WriteHeader(status int) {
-	w.Header().Del("Content-Length")
-	w.ResponseWriter.WriteHeader(status)
-}
+// stringsFlag is a flag which can be repeated
+type stringsFlag []string
 
-func (w *gzipResponseWriter) Write(b []byte) (int, error) {
-	return w.Writer.Write(b)
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
 }
 
-func handleReq(h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if *httpsPromote && r.Header.Get("X-Forwarded-Proto") == "http" {
-			http.Redirect(w, r, "https://"+r.Host+r.RequestURI, http.StatusMovedPermanently)
-			if *logRequest {
-				log.Println(301, r.Method, r.URL.Path)
-			}
-			return
-		}
-
-		if *logRequest {
-			log.Println(r.Method, r.URL.Path)
-		}
-
-		h.ServeHTTP(w, r)
-	})
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
 }
 
-func parseFallbackPage() {
-	if len(flag.Args()) % 2 != 0 {
-		log.Println("Passing variables to be replaced on base file needs to be done by pair var value")
-		os.Exit(1)
-	}
-
-	data, err := ioutil.ReadFile(*basePath + *fallbackPath)
-
-	if err != nil {
-		log.Println("Unable to open file " + *basePath + *fallbackPath)
-		os.Exit(2)
-	}
-
-	page := string(data)
-
-	for i := len(flag.Args()) -1; i >= 1; i -= 2 {
-		regex := regexp.MustCompile(`'`+flag.Arg(i-1)+`' *: *'[^']*'`)
-		page = regex.ReplaceAllString(page, `'`+flag.Arg(i-1)+`':'`+flag.Arg(i)+`'`)
-	}
-
-	defaultPageBytes = []byte(page)
-
-	err = ioutil.WriteFile(*basePath + *fallbackPath, defaultPageBytes, 0644)
-
-	if err != nil {
-		log.Println("Unable to write file " + *basePath + *fallbackPath)
-		os.Exit(3)
+// parsePorts returns the comma separated ports, or port when there are none
+func parsePorts(ports string, port int) ([]int, error) {
+	if ports == "" {
+		return []int{port}, nil
 	}
-
-}
-
-func defaultPage(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-		if len(r.RequestURI) == 0 || r.URL.RequestURI() == "/" || r.URL.RequestURI() == *fallbackPath  {
-			log.Println("Passing here " + r.URL.RequestURI())
-			w.Header().Set("Content-Type", "text/html") // clarify return type (MIME)
-			_, _ = w.Write(defaultPageBytes)
-		} else {
-			next.ServeHTTP(w, r)
+	var parsed []int
+	for _, p := range strings.Split(ports, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || n < 0 || n > 65535 {
+			return nil, fmt.Errorf("invalid port %q in --ports", p)
 		}
-	})
+		parsed = append(parsed, n)
+	}
+	return parsed, nil
 }
 
 func main() {
 
+	flag.Var(&queryHeaders, "query-header", "Response header set when a query parameter is present, specified as `param=HeaderName:Value`, e.g. 'download=Content-Disposition:attachment'. Can be repeated")
+	flag.Var(&fallbackPrefixes, "fallback-prefix", "Only fall back for missing files under this path prefix, e.g. '/app/'. Can be repeated")
+	flag.Var(&allowIPs, "allow-ip", "Only serve the clients in this network, e.g. '10.0.0.0/8'. Can be repeated")
+	flag.Var(&denyIPs, "deny-ip", "Answer 403 to the clients in this network, even when allowed, e.g. '192.168.1.0/24'. Can be repeated")
+	flag.Var(&authPaths, "auth-path", "Only ask for basic auth under this path prefix, e.g. '/admin/'. Can be repeated")
 	flag.Parse()
-
-	// sanity check
-	if len(*setBasicAuth) != 0 && !*basicAuth {
-		*basicAuth = true
+	if err := applyConfig(); err != nil {
+		log.Fatalln(err)
 	}
 
-	port := ":" + strconv.FormatInt(int64(*portPtr), 10)
-
-	var fileSystem http.FileSystem = http.Dir(*basePath)
-
-	if *fallbackPath != "" {
-		fileSystem = fallback{
-			defaultPath: *fallbackPath,
-			fs:          fileSystem,
-		}
+	if err := setLogOutput(*logOutput); err != nil {
+		log.Fatalln(err)
 	}
-
-	handler := handleReq(http.FileServer(fileSystem))
-
-	if *fallbackPath != "" {
-		parseFallbackPage()
-		handler = defaultPage(handler)
+	if *logAsync {
+		setAsyncLog(*logAsyncBuffer, *logAsyncDrop)
 	}
-
-	pathPrefix := "/"
-	if len(*context) > 0 {
-		pathPrefix = "/" + *context + "/"
-		handler = http.StripPrefix(pathPrefix, handler)
+	if err := setLogTimeFormat(*logTimeFormat, *logTimezone); err != nil {
+		log.Fatalln(err)
 	}
 
-	if *basicAuth {
-		log.Println("Enabling Basic Auth")
-		if len(*setBasicAuth) != 0 {
-			parseAuth(*setBasicAuth)
-		} else {
-			generateRandomAuth()
-		}
-		handler = authMiddleware(handler)
+	ports, err := parsePorts(*portsFlag, *portPtr)
+	if err != nil {
+		log.Fatalln(err)
 	}
 
-	headerConfigValid := initHeaderConfig(*headerConfigPath)
-	if headerConfigValid {
-		handler = customHeadersMiddleware(handler)
+	cfg := gostatic.Config{
+		Path:                    *basePath,
+		Context:                 *contextFlag,
+		ContextRoot:             *contextRoot,
+		Origin:                  *origin,
+		OriginCacheTTL:          *originCacheTTL,
+		StaleWhileRevalidate:    *staleWhileRevalidate,
+		Fallback:                *fallbackPath,
+		FallbackPrefixes:        fallbackPrefixes,
+		FallbackVariables:       flag.Args(),
+		FailOnMissingFallback:   *failOnMissingFallback,
+		StrictSubstitution:      *strictSubstitution,
+		SPA:                     *spa,
+		CleanURLs:               *cleanURLs,
+		CleanURLExtensions:      *cleanURLExtensions,
+		DisableDirectoryListing: *disableDirectoryListing,
+		MaxFileSize:             *maxFileSize,
+		RespectSaveData:         *respectSaveData,
+		DefaultFavicon:          *defaultFaviconFlag,
+		EmptyRootMessage:        *emptyRootMessage,
+		EnableManifest:          *enableManifest,
+		ManifestPath:            *manifestPath,
+		ManifestFields:          *manifestFields,
+		InjectSRI:               *injectSRIFlag,
+		ServeStale:              *serveStale,
+		ServeStaleSize:          *serveStaleSize,
+		ServeStaleMaxAge:        *serveStaleMaxAge,
+		NotFoundPage:            *notFoundPage,
+		ForbiddenPage:           *forbiddenPage,
+		RejectDoubleEncoding:    *rejectDoubleEncoding,
+
+		AppendHeader:        *headerFlag,
+		HeaderConfigPath:    *headerConfigPath,
+		DefaultCacheControl: *defaultCacheControl,
+		HTMLNoCache:         *htmlNoCache,
+		ImmutablePattern:    *immutablePattern,
+		QueryHeaders:        queryHeaders,
+		DateHeader:          *dateHeader,
+		FixedDate:           *fixedDate,
+		EnableETag:          *enableETag,
+		ETagVersion:         *etagVersion,
+		EnableCORS:          *enableCORS,
+		CORSAllowOrigin:     *corsAllowOrigin,
+		CORSAllowMethods:    *corsAllowMethods,
+		CORSAllowHeaders:    *corsAllowHeaders,
+
+		DisableCompression:     *disableCompression,
+		NoCompressSetVary:      *noCompressSetVary,
+		DisableBrotli:          *disableBrotli,
+		EncodingPreference:     *encodingPreference,
+		ServePrecompressed:     *servePrecompressed,
+		GzipLevel:              *gzipLevel,
+		GzipTypes:              *gzipTypesFlag,
+		GzipMinSize:            *gzipMinSize,
+		GzipSkipPath:           *gzipSkipPath,
+		GzipSkipUA:             *gzipSkipUA,
+		GzipListingsOnly:       *gzipListingsOnly,
+		NoGzipHTTP2:            *noGzipHTTP2,
+		AllowGzipLevelOverride: *allowGzipLevelOverride,
+		GzipLevelOverrideFrom:  *gzipLevelOverrideFrom,
+		CacheCompressed:        *cacheCompressed,
+		CacheCompressedSize:    *cacheCompressedSize,
+		CacheDebugHeaders:      *cacheDebugHeaders,
+
+		BasicAuth:            *basicAuth,
+		BasicAuthCredentials: *setBasicAuth,
+		BasicAuthFile:        *basicAuthFile,
+		DefaultUser:          *defaultUsernameBasicAuth,
+		PasswordLength:       *sizeRandom,
+		AuthPaths:            authPaths,
+
+		HTTPSPromote:        *httpsPromote,
+		CanonicalHost:       *canonicalHost,
+		DefaultHost:         *defaultHost,
+		RedirectBody:        *redirectBody,
+		RedirectsConfig:     *redirectsConfig,
+		TrustXForwardedHost: *trustXForwardedHost,
+		TrustForwardedFor:   *trustForwardedFor,
+		TrustedProxies:      *trustedProxies,
+		AllowIPs:            allowIPs,
+		DenyIPs:             denyIPs,
+
+		EnableHealth:      *healthCheck,
+		HealthPath:        *healthPath,
+		HealthBody:        *healthBody,
+		HealthMaxInFlight: *healthMaxInFlight,
+		HealthOnly:        *healthOnly,
+		PrestopGrace:      *prestopGrace,
+
+		EnableLogging:  *logRequest,
+		LogFormat:      *logFormat,
+		LogFields:      *logFieldsFlag,
+		LogHeaders:     *logHeaders,
+		AccessLog:      logOutputWriter,
+		GeoIPDB:        *geoIPDB,
+		VerboseStartup: *verboseStartup,
+
+		Ports:             ports,
+		UnixSocket:        *unixSocket,
+		Cert:              *certFile,
+		Key:               *keyFile,
+		DisableHTTP2:      *disableHTTP2,
+		HTTPRedirectPort:  *httpRedirectPort,
+		TLSTicketRotation: *tlsTicketRotation,
+		TCPKeepAlive:      *tcpKeepAlive,
+		ReadTimeout:       *readTimeout,
+		WriteTimeout:      *writeTimeout,
+		IdleTimeout:       *idleTimeout,
+		DrainTimeout:      *drainTimeout,
+		ShutdownTimeout:   *shutdownTimeout,
+		SelfTest:          *selfTest,
 	}
-
-	// Extra headers.
-	if len(*headerFlag) > 0 {
-		header, headerValue := parseHeaderFlag(*headerFlag)
-		if len(header) > 0 && len(headerValue) > 0 {
-			fileServer := handler
-			handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				w.Header().Set(header, headerValue)
-				if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-					fileServer.ServeHTTP(w, r)
-				} else {
-					w.Header().Set("Content-Encoding", "gzip")
-					gz := gzPool.Get().(*gzip.Writer)
-					defer gzPool.Put(gz)
-
-					gz.Reset(w)
-					defer gz.Close()
-					fileServer.ServeHTTP(&gzipResponseWriter{ResponseWriter: w, Writer: gz}, r)
-				}
-
-			})
-		} else {
-			log.Println("appendHeader misconfigured; ignoring.")
-		}
+	server, err := gostatic.New(cfg)
+	if err != nil {
+		log.Fatalln(err)
 	}
 
-	if *healthCheck {
-		http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-			_, _ = fmt.Fprintf(w, "Ok")
-		})
+	startAsyncLog()
+	err = server.ListenAndServe()
+	flushLogs()
+	if err != nil {
+		log.Fatalln(err)
 	}
-
-	http.Handle(pathPrefix, handler)
-
-	log.Printf("Listening at 0.0.0.0%v %v...", port, pathPrefix)
-	log.Fatalln(http.ListenAndServe(port, nil))
 }
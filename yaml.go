@@ -0,0 +1,11 @@
+//go:build yaml
+// +build yaml
+
+package main
+
+import "gopkg.in/yaml.v2"
+
+func init() {
+	configDecoders[".yaml"] = yaml.Unmarshal
+	configDecoders[".yml"] = yaml.Unmarshal
+}